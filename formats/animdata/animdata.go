@@ -0,0 +1,117 @@
+package animdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	EventNone = iota
+	EventAttack
+	EventMissile
+	EventSound
+	EventSkill
+)
+
+const (
+	blockCount   = 256
+	eventCount   = 144
+	maxRecords   = 0x10000
+	speedDivisor = 256
+	tickDuration = time.Second / 25
+)
+
+type recordData struct {
+	Name         [8]byte
+	FramesPerDir uint32
+	Speed        uint32
+	Events       [eventCount]byte
+}
+
+type Record struct {
+	Name         string
+	FramesPerDir int
+	Speed        int
+	Events       [eventCount]byte
+}
+
+type AnimData struct {
+	blocks [blockCount][]Record
+}
+
+func NewFromReader(reader io.Reader) (*AnimData, error) {
+	animData := new(AnimData)
+	for i := range animData.blocks {
+		var recordCount uint32
+		if err := binary.Read(reader, binary.LittleEndian, &recordCount); err != nil {
+			return nil, err
+		}
+
+		if recordCount > maxRecords {
+			return nil, errors.New("invalid record count")
+		}
+
+		records := make([]recordData, recordCount)
+		if err := binary.Read(reader, binary.LittleEndian, records); err != nil {
+			return nil, err
+		}
+
+		for _, data := range records {
+			name := data.Name[:]
+			if index := bytes.IndexByte(name, 0); index >= 0 {
+				name = name[:index]
+			}
+
+			record := Record{
+				Name:         string(name),
+				FramesPerDir: int(data.FramesPerDir),
+				Speed:        int(data.Speed),
+				Events:       data.Events,
+			}
+
+			animData.blocks[i] = append(animData.blocks[i], record)
+		}
+	}
+
+	return animData, nil
+}
+
+func Hash(name string) byte {
+	var hash byte
+	for _, char := range []byte(strings.ToUpper(name)) {
+		hash += char
+	}
+
+	return hash
+}
+
+func (a *AnimData) Find(name string) *Record {
+	records := a.blocks[Hash(name)]
+	for i := range records {
+		if strings.EqualFold(records[i].Name, name) {
+			return &records[i]
+		}
+	}
+
+	return nil
+}
+
+func (r *Record) Event(frameIndex int) int {
+	if frameIndex < 0 || frameIndex >= len(r.Events) {
+		return EventNone
+	}
+
+	return int(r.Events[frameIndex])
+}
+
+func (r *Record) FrameDuration() time.Duration {
+	if r.Speed <= 0 {
+		return 0
+	}
+
+	return tickDuration * speedDivisor / time.Duration(r.Speed)
+}
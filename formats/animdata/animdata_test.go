@@ -0,0 +1,82 @@
+package animdata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+func buildAnimData(records ...recordData) []byte {
+	var blocks [blockCount][]recordData
+	for _, record := range records {
+		name := string(bytes.TrimRight(record.Name[:], "\x00"))
+		hash := Hash(name)
+		blocks[hash] = append(blocks[hash], record)
+	}
+
+	var buff bytes.Buffer
+	for _, block := range blocks {
+		binary.Write(&buff, binary.LittleEndian, uint32(len(block)))
+		binary.Write(&buff, binary.LittleEndian, block)
+	}
+
+	return buff.Bytes()
+}
+
+func TestHash(t *testing.T) {
+	var expected byte
+	for _, char := range []byte("AMA1HTH") {
+		expected += char
+	}
+
+	if hash := Hash("AMA1HTH"); hash != expected {
+		t.Errorf("hash: %d", hash)
+	}
+
+	if Hash("ama1hth") != Hash("AMA1HTH") {
+		t.Error("hash is case sensitive")
+	}
+}
+
+func TestFind(t *testing.T) {
+	attack := recordData{Name: [8]byte{'A', 'M', 'A', '1', 'H', 'T', 'H'}, FramesPerDir: 16, Speed: 256}
+	attack.Events[9] = EventAttack
+
+	walk := recordData{Name: [8]byte{'B', 'A', 'W', 'L', 'H', 'T', 'H'}, FramesPerDir: 8, Speed: 128}
+
+	animData, err := NewFromReader(bytes.NewReader(buildAnimData(attack, walk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := animData.Find("ama1hth")
+	if record == nil {
+		t.Fatal("record not found")
+	}
+
+	if record.Name != "AMA1HTH" || record.FramesPerDir != 16 || record.Speed != 256 {
+		t.Errorf("record: %+v", record)
+	}
+
+	if record.Event(9) != EventAttack || record.Event(8) != EventNone || record.Event(-1) != EventNone {
+		t.Errorf("events: %v", record.Events)
+	}
+
+	if duration := record.FrameDuration(); duration != 40*time.Millisecond {
+		t.Errorf("frame duration: %v", duration)
+	}
+
+	if record := animData.Find("BAWLHTH"); record == nil || record.FrameDuration() != 80*time.Millisecond {
+		t.Errorf("walk record: %+v", record)
+	}
+
+	if record := animData.Find("AMA2HTH"); record != nil {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	data := buildAnimData(attack)
+	if _, err := NewFromReader(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("truncated file was accepted")
+	}
+}
@@ -0,0 +1,178 @@
+package cof
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+const (
+	CompositeHead = iota
+	CompositeTorso
+	CompositeLegs
+	CompositeRightArm
+	CompositeLeftArm
+	CompositeRightHand
+	CompositeLeftHand
+	CompositeShield
+	CompositeSpecial1
+	CompositeSpecial2
+	CompositeSpecial3
+	CompositeSpecial4
+	CompositeSpecial5
+	CompositeSpecial6
+	CompositeSpecial7
+	CompositeSpecial8
+	CompositeCount
+)
+
+var CompositeCodes = [CompositeCount]string{
+	"HD", "TR", "LG", "RA", "LA", "RH", "LH", "SH", "S1", "S2", "S3", "S4", "S5", "S6", "S7", "S8",
+}
+
+const (
+	DrawEffectAlpha25 = iota
+	DrawEffectAlpha50
+	DrawEffectAlpha75
+	DrawEffectModulate
+	DrawEffectBurn
+	DrawEffectNormal
+	DrawEffectMod2XTrans
+	DrawEffectMod2X
+	DrawEffectNone
+)
+
+const (
+	EventNone = iota
+	EventAttack
+	EventMissile
+	EventSound
+	EventSkill
+)
+
+type fileHeader struct {
+	LayerCount   uint8
+	FramesPerDir uint8
+	DirCount     uint8
+	Version      uint8
+	_            uint32
+	MinX         int32
+	MaxX         int32
+	MinY         int32
+	MaxY         int32
+	Speed        int16
+	_            int16
+}
+
+type layerHeader struct {
+	Type        uint8
+	Shadow      uint8
+	Selectable  uint8
+	Transparent uint8
+	DrawEffect  uint8
+	WeaponClass [4]byte
+}
+
+type Layer struct {
+	Type        int
+	Shadow      bool
+	Selectable  bool
+	Transparent bool
+	DrawEffect  int
+	WeaponClass string
+}
+
+type Animation struct {
+	Directions   int
+	FramesPerDir int
+	Bounds       math.Rect4i
+	Speed        int
+	Layers       []Layer
+	Events       []byte
+	Priority     [][][]int
+}
+
+func NewFromReader(reader io.Reader) (*Animation, error) {
+	var fileHead fileHeader
+	if err := binary.Read(reader, binary.LittleEndian, &fileHead); err != nil {
+		return nil, err
+	}
+
+	anim := &Animation{
+		Directions:   int(fileHead.DirCount),
+		FramesPerDir: int(fileHead.FramesPerDir),
+		Bounds: math.Rect4i{
+			X: int(fileHead.MinX),
+			Y: int(fileHead.MinY),
+			W: int(fileHead.MaxX - fileHead.MinX),
+			H: int(fileHead.MaxY - fileHead.MinY),
+		},
+		Speed: int(fileHead.Speed),
+	}
+
+	layerHeads := make([]layerHeader, fileHead.LayerCount)
+	if err := binary.Read(reader, binary.LittleEndian, layerHeads); err != nil {
+		return nil, err
+	}
+
+	for _, layerHead := range layerHeads {
+		if layerHead.Type >= CompositeCount {
+			return nil, errors.New("invalid layer type")
+		}
+
+		weaponClass := layerHead.WeaponClass[:]
+		if index := bytes.IndexByte(weaponClass, 0); index >= 0 {
+			weaponClass = weaponClass[:index]
+		}
+
+		anim.Layers = append(anim.Layers, Layer{
+			Type:        int(layerHead.Type),
+			Shadow:      layerHead.Shadow != 0,
+			Selectable:  layerHead.Selectable != 0,
+			Transparent: layerHead.Transparent != 0,
+			DrawEffect:  int(layerHead.DrawEffect),
+			WeaponClass: string(weaponClass),
+		})
+	}
+
+	anim.Events = make([]byte, anim.FramesPerDir)
+	if _, err := io.ReadFull(reader, anim.Events); err != nil {
+		return nil, err
+	}
+
+	priority := make([]byte, anim.Directions*anim.FramesPerDir*len(anim.Layers))
+	if _, err := io.ReadFull(reader, priority); err != nil {
+		return nil, err
+	}
+
+	anim.Priority = make([][][]int, anim.Directions)
+	for i := range anim.Priority {
+		anim.Priority[i] = make([][]int, anim.FramesPerDir)
+		for j := range anim.Priority[i] {
+			order := make([]int, len(anim.Layers))
+			for k := range order {
+				order[k] = int(priority[(i*anim.FramesPerDir+j)*len(anim.Layers)+k])
+				if order[k] >= CompositeCount {
+					return nil, errors.New("invalid layer priority")
+				}
+			}
+
+			anim.Priority[i][j] = order
+		}
+	}
+
+	return anim, nil
+}
+
+func (a *Animation) Layer(compositeType int) *Layer {
+	for i := range a.Layers {
+		if a.Layers[i].Type == compositeType {
+			return &a.Layers[i]
+		}
+	}
+
+	return nil
+}
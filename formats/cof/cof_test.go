@@ -0,0 +1,91 @@
+package cof
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+func buildAnimation() []byte {
+	var buff bytes.Buffer
+	binary.Write(&buff, binary.LittleEndian, fileHeader{
+		LayerCount:   2,
+		FramesPerDir: 3,
+		DirCount:     2,
+		Version:      20,
+		MinX:         -20,
+		MaxX:         21,
+		MinY:         -80,
+		MaxY:         10,
+		Speed:        256,
+	})
+
+	binary.Write(&buff, binary.LittleEndian, []layerHeader{
+		{Type: CompositeTorso, Shadow: 1, Selectable: 1, WeaponClass: [4]byte{'h', 't', 'h', 0}},
+		{Type: CompositeRightHand, Transparent: 1, DrawEffect: DrawEffectAlpha50, WeaponClass: [4]byte{'1', 'h', 's', 0}},
+	})
+
+	buff.Write([]byte{EventNone, EventAttack, EventNone})
+	buff.Write([]byte{
+		CompositeTorso, CompositeRightHand,
+		CompositeTorso, CompositeRightHand,
+		CompositeRightHand, CompositeTorso,
+		CompositeRightHand, CompositeTorso,
+		CompositeRightHand, CompositeTorso,
+		CompositeTorso, CompositeRightHand,
+	})
+
+	return buff.Bytes()
+}
+
+func TestNewFromReader(t *testing.T) {
+	anim, err := NewFromReader(bytes.NewReader(buildAnimation()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if anim.Directions != 2 || anim.FramesPerDir != 3 || anim.Speed != 256 {
+		t.Errorf("header: %+v", anim)
+	}
+
+	if anim.Bounds != (math.Rect4i{X: -20, Y: -80, W: 41, H: 90}) {
+		t.Errorf("bounds: %+v", anim.Bounds)
+	}
+
+	expectedLayers := []Layer{
+		{Type: CompositeTorso, Shadow: true, Selectable: true, WeaponClass: "hth"},
+		{Type: CompositeRightHand, Transparent: true, DrawEffect: DrawEffectAlpha50, WeaponClass: "1hs"},
+	}
+
+	if !reflect.DeepEqual(anim.Layers, expectedLayers) {
+		t.Errorf("layers: %+v, expected: %+v", anim.Layers, expectedLayers)
+	}
+
+	if !bytes.Equal(anim.Events, []byte{EventNone, EventAttack, EventNone}) {
+		t.Errorf("events: %v", anim.Events)
+	}
+
+	if order := anim.Priority[0][2]; !reflect.DeepEqual(order, []int{CompositeRightHand, CompositeTorso}) {
+		t.Errorf("priority: %v", order)
+	}
+
+	if order := anim.Priority[1][2]; !reflect.DeepEqual(order, []int{CompositeTorso, CompositeRightHand}) {
+		t.Errorf("priority: %v", order)
+	}
+
+	if layer := anim.Layer(CompositeRightHand); layer == nil || layer.WeaponClass != "1hs" {
+		t.Errorf("layer lookup: %+v", layer)
+	}
+
+	if layer := anim.Layer(CompositeHead); layer != nil {
+		t.Errorf("missing layer lookup: %+v", layer)
+	}
+
+	data := buildAnimation()
+	if _, err := NewFromReader(bytes.NewReader(data[:len(data)-1])); err == nil {
+		t.Error("truncated file was accepted")
+	}
+}
@@ -0,0 +1,125 @@
+package d2s
+
+import (
+	"bytes"
+
+	"github.com/FooSoft/lazarus/streaming"
+)
+
+type bitCodec struct {
+	reader  *streaming.BitReader
+	writer  *streaming.BitWriter
+	failure error
+}
+
+func newBitDecoder(data []byte) *bitCodec {
+	return &bitCodec{reader: streaming.NewBitReader(bytes.NewReader(data))}
+}
+
+func newBitEncoder() *bitCodec {
+	return &bitCodec{writer: streaming.NewBitWriter()}
+}
+
+func (c *bitCodec) decoding() bool {
+	return c.reader != nil
+}
+
+func (c *bitCodec) uint(value *uint32, count int) {
+	if c.decoding() {
+		*value = uint32(c.reader.ReadUint(count))
+	} else {
+		c.writer.WriteUint(uint64(*value), count)
+	}
+}
+
+func (c *bitCodec) uint16(value *uint16) {
+	temp := uint32(*value)
+	c.uint(&temp, 16)
+	*value = uint16(temp)
+}
+
+func (c *bitCodec) int(value *int, count int) {
+	if c.decoding() {
+		*value = int(c.reader.ReadUint(count))
+	} else {
+		c.writer.WriteUint(uint64(*value), count)
+	}
+}
+
+func (c *bitCodec) intAdd(value *int, count, add int) {
+	if c.decoding() {
+		*value = int(c.reader.ReadUint(count)) - add
+	} else {
+		c.writer.WriteUint(uint64(*value+add), count)
+	}
+}
+
+func (c *bitCodec) bool(value *bool) {
+	if c.decoding() {
+		*value = c.reader.ReadBool()
+	} else {
+		c.writer.WriteBool(*value)
+	}
+}
+
+func (c *bitCodec) string(value *string, charBits int) {
+	if c.decoding() {
+		var chars []byte
+		for {
+			char := byte(c.reader.ReadUint(charBits))
+			if char == 0 || c.reader.Error() != nil {
+				break
+			}
+
+			chars = append(chars, char)
+		}
+
+		*value = string(chars)
+	} else {
+		for _, char := range []byte(*value) {
+			c.writer.WriteUint(uint64(char), charBits)
+		}
+
+		c.writer.WriteUint(0, charBits)
+	}
+}
+
+func (c *bitCodec) bytes(data []byte) {
+	for i := range data {
+		temp := uint32(data[i])
+		c.uint(&temp, 8)
+		data[i] = byte(temp)
+	}
+}
+
+func (c *bitCodec) signature(signature string) bool {
+	data := []byte(signature)
+	c.bytes(data)
+	return string(data) == signature
+}
+
+func (c *bitCodec) align() {
+	if c.decoding() {
+		c.reader.ReadUint((8 - c.reader.Offset()%8) % 8)
+	} else {
+		c.writer.Align()
+	}
+}
+
+func (c *bitCodec) fail(err error) {
+	if c.failure == nil {
+		c.failure = err
+	}
+}
+
+func (c *bitCodec) err() error {
+	if c.failure != nil {
+		return c.failure
+	}
+
+	if c.decoding() {
+		return c.reader.Error()
+	}
+
+	return nil
+}
@@ -0,0 +1,342 @@
+package d2s
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+const (
+	Version109 = 92
+	Version110 = 96
+)
+
+const (
+	fileSignature  = 0xaa55aa55
+	checksumOffset = 12
+	questVersion   = 6
+	questSize      = 298
+	waypointSize   = 80
+	npcSize        = 52
+)
+
+const (
+	StatusHardcore  = 1 << 2
+	StatusDied      = 1 << 3
+	StatusExpansion = 1 << 5
+)
+
+const (
+	ClassAmazon = iota
+	ClassSorceress
+	ClassNecromancer
+	ClassPaladin
+	ClassBarbarian
+	ClassDruid
+	ClassAssassin
+)
+
+type Header struct {
+	Signature      uint32
+	Version        uint32
+	FileSize       uint32
+	Checksum       uint32
+	ActiveWeapon   uint32
+	Name           [16]byte
+	Status         uint8
+	Progression    uint8
+	Unknown1       [2]byte
+	Class          uint8
+	Unknown2       [2]byte
+	Level          uint8
+	Unknown3       [4]byte
+	LastPlayed     uint32
+	Unknown4       [4]byte
+	Hotkeys        [16]uint32
+	LeftSkill      uint32
+	RightSkill     uint32
+	LeftSwapSkill  uint32
+	RightSwapSkill uint32
+	Appearance     [32]byte
+	Difficulty     [3]byte
+	MapId          uint32
+	Unknown5       [2]byte
+	MercDead       uint16
+	MercId         uint32
+	MercNameId     uint16
+	MercType       uint16
+	MercExperience uint32
+	Unknown6       [144]byte
+}
+
+type Stat struct {
+	Id    int
+	Param int
+	Value int
+}
+
+type Corpse struct {
+	Unknown  uint32
+	Position math.Vec2i
+	Items    []Item
+}
+
+type Character struct {
+	Header    Header
+	Quests    [3][48]uint16
+	Waypoints [3][24]byte
+	Npcs      [48]byte
+	Stats     []Stat
+	Skills    [30]byte
+	Items     []Item
+	Corpses   []Corpse
+	MercItems []Item
+	Golem     *Item
+}
+
+type saveCodec struct {
+	*bitCodec
+	tables  *Tables
+	version int
+}
+
+func NewFromReader(reader io.Reader, tables *Tables) (*Character, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	char := new(Character)
+	if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &char.Header); err != nil {
+		return nil, err
+	}
+
+	if char.Header.Signature != fileSignature {
+		return nil, errors.New("invalid file signature")
+	}
+
+	if !supportedVersion(int(char.Header.Version)) {
+		return nil, errors.New("unsupported file version")
+	}
+
+	if int(char.Header.FileSize) != len(data) {
+		return nil, errors.New("invalid file size")
+	}
+
+	if checksum(data) != char.Header.Checksum {
+		return nil, errors.New("invalid checksum")
+	}
+
+	s := &saveCodec{newBitDecoder(data[binary.Size(char.Header):]), tables, int(char.Header.Version)}
+	if s.character(char); s.err() != nil {
+		return nil, s.err()
+	}
+
+	return char, nil
+}
+
+func (c *Character) Write(writer io.Writer, tables *Tables) error {
+	if !supportedVersion(int(c.Header.Version)) {
+		return errors.New("unsupported file version")
+	}
+
+	s := &saveCodec{newBitEncoder(), tables, int(c.Header.Version)}
+	if s.character(c); s.err() != nil {
+		return s.err()
+	}
+
+	body := s.writer.Bytes()
+
+	c.Header.Signature = fileSignature
+	c.Header.FileSize = uint32(binary.Size(c.Header) + len(body))
+	c.Header.Checksum = 0
+
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.LittleEndian, c.Header); err != nil {
+		return err
+	}
+
+	buffer.Write(body)
+
+	data := buffer.Bytes()
+	c.Header.Checksum = checksum(data)
+	binary.LittleEndian.PutUint32(data[checksumOffset:], c.Header.Checksum)
+
+	_, err := writer.Write(data)
+	return err
+}
+
+func (c *Character) Name() string {
+	name := c.Header.Name[:]
+	if index := bytes.IndexByte(name, 0); index >= 0 {
+		name = name[:index]
+	}
+
+	return string(name)
+}
+
+func (c *Character) Expansion() bool {
+	return c.Header.Status&StatusExpansion != 0
+}
+
+func (s *saveCodec) character(char *Character) {
+	s.section("Woo!", questVersion, questSize)
+	for i := range char.Quests {
+		for j := range char.Quests[i] {
+			s.uint16(&char.Quests[i][j])
+		}
+	}
+
+	s.section("WS", 1, waypointSize)
+	for i := range char.Waypoints {
+		s.bytes(char.Waypoints[i][:])
+	}
+
+	if !s.signature("\x01w") {
+		s.fail(errors.New("invalid npc section"))
+	}
+
+	size := npcSize
+	if s.int(&size, 16); size != npcSize {
+		s.fail(errors.New("invalid npc section size"))
+	}
+
+	s.bytes(char.Npcs[:])
+
+	if !s.signature("gf") {
+		s.fail(errors.New("invalid stat section"))
+	}
+
+	s.stats(&char.Stats)
+	s.align()
+
+	if !s.signature("if") {
+		s.fail(errors.New("invalid skill section"))
+	}
+
+	s.bytes(char.Skills[:])
+	s.itemList(&char.Items)
+
+	if !s.signature("JM") {
+		s.fail(errors.New("invalid corpse section"))
+	}
+
+	corpses := len(char.Corpses)
+	s.int(&corpses, 16)
+	if s.decoding() && corpses > 0 {
+		char.Corpses = make([]Corpse, corpses)
+	}
+
+	for i := range char.Corpses {
+		if s.err() != nil {
+			return
+		}
+
+		corpse := &char.Corpses[i]
+		s.uint(&corpse.Unknown, 32)
+		s.int(&corpse.Position.X, 32)
+		s.int(&corpse.Position.Y, 32)
+		s.itemList(&corpse.Items)
+	}
+
+	if !char.Expansion() {
+		return
+	}
+
+	if !s.signature("jf") {
+		s.fail(errors.New("invalid mercenary section"))
+	}
+
+	if char.Header.MercId != 0 {
+		s.itemList(&char.MercItems)
+	}
+
+	if !s.signature("kf") {
+		s.fail(errors.New("invalid golem section"))
+	}
+
+	var golem int
+	if char.Golem != nil {
+		golem = 1
+	}
+
+	if s.int(&golem, 8); golem != 0 {
+		if s.decoding() {
+			char.Golem = new(Item)
+		}
+
+		s.item(char.Golem)
+	}
+}
+
+func (s *saveCodec) section(signature string, version, size int) {
+	valid := s.signature(signature)
+
+	sectionVersion, sectionSize := version, size
+	s.int(&sectionVersion, 32)
+	s.int(&sectionSize, 16)
+
+	if !valid || sectionVersion != version || sectionSize != size {
+		s.fail(fmt.Errorf("invalid %q section", signature))
+	}
+}
+
+func (s *saveCodec) stats(stats *[]Stat) {
+	if s.decoding() {
+		for s.err() == nil {
+			var id int
+			if s.int(&id, 9); id == statListEnd {
+				break
+			}
+
+			stat := Stat{Id: id}
+			s.characterStat(&stat)
+			*stats = append(*stats, stat)
+		}
+	} else {
+		for _, stat := range *stats {
+			s.int(&stat.Id, 9)
+			s.characterStat(&stat)
+		}
+
+		end := statListEnd
+		s.int(&end, 9)
+	}
+}
+
+func (s *saveCodec) characterStat(stat *Stat) {
+	cost, err := s.tables.stat(stat.Id)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	if cost.CsvBits == 0 {
+		s.fail(fmt.Errorf("stat %d is not saved", stat.Id))
+		return
+	}
+
+	s.int(&stat.Param, cost.CsvParam)
+	s.int(&stat.Value, cost.CsvBits)
+}
+
+func supportedVersion(version int) bool {
+	return version == Version109 || version == Version110
+}
+
+func checksum(data []byte) uint32 {
+	var sum uint32
+	for i, value := range data {
+		if i >= checksumOffset && i < checksumOffset+4 {
+			value = 0
+		}
+
+		sum = (sum<<1 | sum>>31) + uint32(value)
+	}
+
+	return sum
+}
@@ -0,0 +1,203 @@
+package d2s
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+var testTables = &Tables{
+	Stats: map[int]StatCost{
+		0:   {CsvBits: 10, SaveBits: 8, SaveAdd: 32, SaveBits109: 8, SaveAdd109: 32},
+		7:   {CsvBits: 21, SaveBits: 9, SaveAdd: 32},
+		12:  {CsvBits: 7},
+		13:  {CsvBits: 32},
+		17:  {SaveBits: 9},
+		18:  {SaveBits: 9},
+		31:  {SaveBits: 11, SaveAdd: 10, SaveBits109: 10, SaveAdd109: 10},
+		54:  {SaveBits: 8},
+		55:  {SaveBits: 9},
+		56:  {SaveBits: 8},
+		72:  {SaveBits: 8},
+		73:  {SaveBits: 8},
+		107: {SaveBits: 3, SaveParamBits: 9},
+	},
+	Items: map[string]ItemType{
+		"cap": {Armor: true},
+		"hax": {Weapon: true},
+		"tbk": {Stackable: true},
+		"r01": {},
+		"jew": {},
+	},
+}
+
+func newTestCharacter(version int) *Character {
+	char := &Character{
+		Stats: []Stat{{Id: 0, Value: 30}, {Id: 7, Value: 55 << 8}, {Id: 12, Value: 42}, {Id: 13, Value: 0xdeadbeef}},
+		Items: []Item{
+			{Flags: FlagIdentified | FlagSimple, Version: 101, Location: LocationBelt, Position: math.Vec2i{X: 3}, Code: "r01"},
+			{
+				Flags:    FlagIdentified | FlagSocketed,
+				Version:  101,
+				Storage:  StorageInventory,
+				Position: math.Vec2i{X: 1, Y: 2},
+				Code:     "hax",
+				Id:       0x12345678,
+				Level:    12,
+				Quality:  QualityMagic,
+				Prefixes: [3]int{5},
+				Suffixes: [3]int{700},
+
+				MaxDurability: 28,
+				Durability:    20,
+				Sockets:       2,
+				Properties:    []Stat{{Id: 17, Value: 50}, {Id: 18, Value: 50}, {Id: 107, Param: 36, Value: 2}},
+				Children: []Item{
+					{Flags: FlagIdentified | FlagSimple, Version: 101, Location: LocationSocketed, Code: "r01"},
+					{Flags: FlagIdentified, Version: 101, Location: LocationSocketed, Position: math.Vec2i{X: 1}, Code: "jew", Quality: QualityRare, RareNames: [2]int{10, 20}, Prefixes: [3]int{1, 0, 3}, Suffixes: [3]int{0, 2}},
+				},
+			},
+			{Flags: FlagEar | FlagSimple, Version: 101, Storage: StorageStash, Ear: Ear{Class: ClassDruid, Level: 90, Name: "Victim"}},
+			{
+				Flags:           FlagIdentified | FlagPersonalized | FlagEthereal,
+				Version:         101,
+				Location:        LocationEquipped,
+				Equipped:        1,
+				Code:            "cap",
+				Quality:         QualitySet,
+				QualityData:     123,
+				HasPicture:      true,
+				Picture:         5,
+				Personalization: "Hero",
+				HasRealmData:    true,
+				RealmData:       [3]uint32{0x01020304, 0xdeadbeef, 0x80000001},
+				Defense:         7,
+				SetMask:         0x5,
+				SetProperties:   [5][]Stat{{{Id: 0, Value: 10}}, nil, {{Id: 54, Value: 3}, {Id: 55, Value: 9}, {Id: 56, Value: 50}}},
+			},
+			{Flags: FlagIdentified | FlagRuneword | FlagSocketed, Version: 101, Code: "cap", Quality: QualitySuperior, QualityData: 2, HasClassData: true, ClassData: 1234, Runeword: 27, RunewordData: 5, Defense: 12, Sockets: 1, RunewordProperties: []Stat{{Id: 0, Value: -5}}},
+			{Flags: FlagIdentified, Version: 101, Code: "tbk", Quality: QualityNormal, TomeData: 4, Quantity: 20},
+		},
+		Corpses:   []Corpse{{Unknown: 1, Position: math.Vec2i{X: 5000, Y: 6000}, Items: []Item{{Flags: FlagSimple, Version: 101, Code: "r01"}}}},
+		MercItems: []Item{{Flags: FlagIdentified, Version: 101, Location: LocationEquipped, Equipped: 4, Code: "hax", Quality: QualityUnique, QualityData: 99}},
+		Golem:     &Item{Flags: FlagIdentified, Version: 101, Code: "cap", Quality: QualityCrafted, Defense: 3, MaxDurability: 10, Durability: 10},
+	}
+
+	char.Header.Version = uint32(version)
+	char.Header.Status = StatusExpansion
+	char.Header.Level = 42
+	char.Header.MercId = 0x1234
+	copy(char.Header.Name[:], "Tester")
+	char.Quests[1][4] = 0x1001
+	char.Waypoints[2][0] = 0x02
+	char.Npcs[47] = 0xff
+	char.Skills[29] = 20
+
+	return char
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, version := range []int{Version109, Version110} {
+		char := newTestCharacter(version)
+
+		var buffer bytes.Buffer
+		if err := char.Write(&buffer, testTables); err != nil {
+			t.Fatal(err)
+		}
+
+		if int(char.Header.FileSize) != buffer.Len() {
+			t.Errorf("file size: %d, expected: %d", char.Header.FileSize, buffer.Len())
+		}
+
+		read, err := NewFromReader(bytes.NewReader(buffer.Bytes()), testTables)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(read, char) {
+			t.Errorf("read character: %+v, expected: %+v", read, char)
+		}
+
+		if read.Name() != "Tester" || !read.Expansion() {
+			t.Errorf("name: %q, expansion: %t", read.Name(), read.Expansion())
+		}
+
+		var rewritten bytes.Buffer
+		if err := read.Write(&rewritten, testTables); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(rewritten.Bytes(), buffer.Bytes()) {
+			t.Errorf("rewritten data differs for version %d", version)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := newTestCharacter(Version110).Write(&buffer, testTables); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buffer.Bytes()
+	data[len(data)-1] ^= 0xff
+	if _, err := NewFromReader(bytes.NewReader(data), testTables); err == nil {
+		t.Error("corrupted data was accepted")
+	}
+
+	if sum := checksum([]byte{0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 1}); sum != 0x800001 {
+		t.Errorf("checksum: %x", sum)
+	}
+}
+
+func TestSimpleItem(t *testing.T) {
+	s := &saveCodec{newBitEncoder(), testTables, Version110}
+	s.item(&Item{Flags: FlagIdentified | FlagSimple, Version: 101, Code: "r01"})
+	if err := s.err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data := s.writer.Bytes(); len(data) != 14 || !bytes.Equal(data[:2], []byte("JM")) {
+		t.Errorf("simple item: %x", data)
+	}
+
+	s = &saveCodec{newBitEncoder(), testTables, Version110}
+	s.item(&Item{Code: "xyz"})
+	if s.err() == nil {
+		t.Error("unknown item code was accepted")
+	}
+}
+
+func TestRealmData(t *testing.T) {
+	encode := func(item *Item) []byte {
+		s := &saveCodec{newBitEncoder(), testTables, Version110}
+		if s.item(item); s.err() != nil {
+			t.Fatal(s.err())
+		}
+
+		return s.writer.Bytes()
+	}
+
+	item := Item{Flags: FlagIdentified, Version: 101, Code: "cap", Quality: QualityNormal, Defense: 3}
+	plain := encode(&item)
+
+	item.HasRealmData = true
+	item.RealmData = [3]uint32{0x11111111, 0x22222222, 0x33333333}
+	data := encode(&item)
+
+	if len(data) != len(plain)+12 {
+		t.Errorf("realm data size: %d, expected: %d", len(data)-len(plain), 12)
+	}
+
+	var read Item
+	s := &saveCodec{newBitDecoder(data), testTables, Version110}
+	if s.item(&read); s.err() != nil {
+		t.Fatal(s.err())
+	}
+
+	if !reflect.DeepEqual(read, item) {
+		t.Errorf("read item: %+v, expected: %+v", read, item)
+	}
+}
@@ -0,0 +1,342 @@
+package d2s
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+const (
+	FlagIdentified   = 1 << 4
+	FlagSocketed     = 1 << 11
+	FlagNew          = 1 << 13
+	FlagEar          = 1 << 16
+	FlagStarter      = 1 << 17
+	FlagSimple       = 1 << 21
+	FlagEthereal     = 1 << 22
+	FlagPersonalized = 1 << 24
+	FlagRuneword     = 1 << 26
+)
+
+const (
+	QualityLow = iota + 1
+	QualityNormal
+	QualitySuperior
+	QualityMagic
+	QualitySet
+	QualityRare
+	QualityUnique
+	QualityCrafted
+)
+
+const (
+	LocationStored   = 0
+	LocationEquipped = 1
+	LocationBelt     = 2
+	LocationCursor   = 4
+	LocationSocketed = 6
+)
+
+const (
+	StorageNone      = 0
+	StorageInventory = 1
+	StorageCube      = 4
+	StorageStash     = 5
+)
+
+const (
+	statListEnd       = 0x1ff
+	statArmorClass    = 31
+	statDurability    = 72
+	statMaxDurability = 73
+)
+
+var statFollowers = map[int][]int{
+	17: {18},
+	48: {49},
+	50: {51},
+	52: {53},
+	54: {55, 56},
+	57: {58, 59},
+}
+
+type Ear struct {
+	Class int
+	Level int
+	Name  string
+}
+
+type Item struct {
+	Flags    uint32
+	Version  int
+	Location int
+	Equipped int
+	Position math.Vec2i
+	Storage  int
+	Code     string
+	Ear      Ear
+
+	Id              uint32
+	Level           int
+	Quality         int
+	HasPicture      bool
+	Picture         int
+	HasClassData    bool
+	ClassData       int
+	QualityData     int
+	RareNames       [2]int
+	Prefixes        [3]int
+	Suffixes        [3]int
+	Runeword        int
+	RunewordData    int
+	Personalization string
+	TomeData        int
+	HasRealmData    bool
+	RealmData       [3]uint32
+	Defense         int
+	MaxDurability   int
+	Durability      int
+	Quantity        int
+	Sockets         int
+	SetMask         int
+
+	Properties         []Stat
+	SetProperties      [5][]Stat
+	RunewordProperties []Stat
+	Children           []Item
+}
+
+func (i *Item) flag(mask uint32) bool {
+	return i.Flags&mask != 0
+}
+
+func (s *saveCodec) itemList(items *[]Item) {
+	if !s.signature("JM") {
+		s.fail(errors.New("invalid item list"))
+		return
+	}
+
+	count := len(*items)
+	s.int(&count, 16)
+	if s.decoding() && count > 0 {
+		*items = make([]Item, count)
+	}
+
+	for i := range *items {
+		if s.item(&(*items)[i]); s.err() != nil {
+			return
+		}
+	}
+}
+
+func (s *saveCodec) item(item *Item) {
+	if !s.signature("JM") {
+		s.fail(errors.New("invalid item"))
+		return
+	}
+
+	s.uint(&item.Flags, 32)
+	s.int(&item.Version, 10)
+	s.int(&item.Location, 3)
+	s.int(&item.Equipped, 4)
+	s.int(&item.Position.X, 4)
+	s.int(&item.Position.Y, 4)
+	s.int(&item.Storage, 3)
+
+	if item.flag(FlagEar) {
+		s.int(&item.Ear.Class, 3)
+		s.int(&item.Ear.Level, 7)
+		s.string(&item.Ear.Name, 7)
+		s.align()
+		return
+	}
+
+	var code [4]byte
+	copy(code[:], fmt.Sprintf("%-4s", item.Code))
+	s.bytes(code[:])
+	item.Code = strings.TrimRight(string(code[:]), " ")
+
+	children := len(item.Children)
+	s.int(&children, 3)
+
+	if !item.flag(FlagSimple) {
+		s.extended(item)
+	}
+
+	s.align()
+
+	if s.decoding() && children > 0 {
+		item.Children = make([]Item, children)
+	}
+
+	for i := range item.Children {
+		if s.item(&item.Children[i]); s.err() != nil {
+			return
+		}
+	}
+}
+
+func (s *saveCodec) extended(item *Item) {
+	itemType, err := s.tables.item(item.Code)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	s.uint(&item.Id, 32)
+	s.int(&item.Level, 7)
+	s.int(&item.Quality, 4)
+
+	if s.bool(&item.HasPicture); item.HasPicture {
+		s.int(&item.Picture, 3)
+	}
+
+	if s.bool(&item.HasClassData); item.HasClassData {
+		s.int(&item.ClassData, 11)
+	}
+
+	switch item.Quality {
+	case QualityLow, QualitySuperior:
+		s.int(&item.QualityData, 3)
+	case QualityMagic:
+		s.int(&item.Prefixes[0], 11)
+		s.int(&item.Suffixes[0], 11)
+	case QualitySet, QualityUnique:
+		s.int(&item.QualityData, 12)
+	case QualityRare, QualityCrafted:
+		s.int(&item.RareNames[0], 8)
+		s.int(&item.RareNames[1], 8)
+		for i := 0; i < 3; i++ {
+			s.affix(&item.Prefixes[i])
+			s.affix(&item.Suffixes[i])
+		}
+	}
+
+	if item.flag(FlagRuneword) {
+		s.int(&item.Runeword, 12)
+		s.int(&item.RunewordData, 4)
+	}
+
+	if item.flag(FlagPersonalized) {
+		s.string(&item.Personalization, 7)
+	}
+
+	if item.Code == "tbk" || item.Code == "ibk" {
+		s.int(&item.TomeData, 5)
+	}
+
+	if s.bool(&item.HasRealmData); item.HasRealmData {
+		for i := range item.RealmData {
+			s.uint(&item.RealmData[i], 32)
+		}
+	}
+
+	if itemType.Armor {
+		s.itemStat(statArmorClass, &item.Defense, nil)
+	}
+
+	if itemType.Armor || itemType.Weapon {
+		s.itemStat(statMaxDurability, &item.MaxDurability, nil)
+		if item.MaxDurability > 0 {
+			s.itemStat(statDurability, &item.Durability, nil)
+		}
+	}
+
+	if itemType.Stackable {
+		s.int(&item.Quantity, 9)
+	}
+
+	if item.flag(FlagSocketed) {
+		s.int(&item.Sockets, 4)
+	}
+
+	if item.Quality == QualitySet {
+		s.int(&item.SetMask, 5)
+	}
+
+	s.properties(&item.Properties)
+
+	if item.Quality == QualitySet {
+		for i := range item.SetProperties {
+			if item.SetMask&(1<<uint(i)) != 0 {
+				s.properties(&item.SetProperties[i])
+			}
+		}
+	}
+
+	if item.flag(FlagRuneword) {
+		s.properties(&item.RunewordProperties)
+	}
+}
+
+func (s *saveCodec) affix(value *int) {
+	present := *value != 0
+	if s.bool(&present); present {
+		s.int(value, 11)
+	}
+}
+
+func (s *saveCodec) itemStat(id int, value, param *int) {
+	stat, err := s.tables.stat(id)
+	if err != nil {
+		s.fail(err)
+		return
+	}
+
+	bits, add := stat.SaveBits, stat.SaveAdd
+	if s.version < Version110 && stat.SaveBits109 > 0 {
+		bits, add = stat.SaveBits109, stat.SaveAdd109
+	}
+
+	if param != nil {
+		s.int(param, stat.SaveParamBits)
+	}
+
+	s.intAdd(value, bits, add)
+}
+
+func (s *saveCodec) properties(stats *[]Stat) {
+	if s.decoding() {
+		for s.err() == nil {
+			var id int
+			if s.int(&id, 9); id == statListEnd {
+				break
+			}
+
+			for _, id := range append([]int{id}, statFollowers[id]...) {
+				stat := Stat{Id: id}
+				s.itemStat(id, &stat.Value, &stat.Param)
+				*stats = append(*stats, stat)
+			}
+		}
+	} else {
+		list := *stats
+		for i := 0; i < len(list); {
+			id := list[i].Id
+			s.int(&id, 9)
+
+			group := append([]int{id}, statFollowers[id]...)
+			if i+len(group) > len(list) {
+				s.fail(fmt.Errorf("incomplete stat group %d", id))
+				return
+			}
+
+			for j, id := range group {
+				stat := list[i+j]
+				if stat.Id != id {
+					s.fail(fmt.Errorf("expected stat %d after stat %d", id, group[0]))
+					return
+				}
+
+				s.itemStat(id, &stat.Value, &stat.Param)
+			}
+
+			i += len(group)
+		}
+
+		end := statListEnd
+		s.int(&end, 9)
+	}
+}
@@ -0,0 +1,112 @@
+package d2s
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/FooSoft/lazarus/formats/txt"
+)
+
+const tableDir = "data/global/excel/"
+
+type StatCost struct {
+	CsvBits       int
+	CsvParam      int
+	SaveBits      int
+	SaveAdd       int
+	SaveParamBits int
+	SaveBits109   int
+	SaveAdd109    int
+}
+
+type ItemType struct {
+	Armor     bool
+	Weapon    bool
+	Stackable bool
+}
+
+type Tables struct {
+	Stats map[int]StatCost
+	Items map[string]ItemType
+}
+
+func LoadTables(fsys fs.FS) (*Tables, error) {
+	var stats []struct {
+		Id            int `txt:"ID"`
+		CsvBits       int `txt:"CSvBits"`
+		CsvParam      int `txt:"CSvParam"`
+		SaveBits      int `txt:"Save Bits"`
+		SaveAdd       int `txt:"Save Add"`
+		SaveParamBits int `txt:"Save Param Bits"`
+		SaveBits109   int `txt:"1.09-Save Bits"`
+		SaveAdd109    int `txt:"1.09-Save Add"`
+	}
+
+	if err := txt.Load(fsys, tableDir+"itemstatcost.txt", &stats); err != nil {
+		return nil, err
+	}
+
+	tables := &Tables{
+		Stats: make(map[int]StatCost),
+		Items: make(map[string]ItemType),
+	}
+
+	for _, stat := range stats {
+		tables.Stats[stat.Id] = StatCost{
+			CsvBits:       stat.CsvBits,
+			CsvParam:      stat.CsvParam,
+			SaveBits:      stat.SaveBits,
+			SaveAdd:       stat.SaveAdd,
+			SaveParamBits: stat.SaveParamBits,
+			SaveBits109:   stat.SaveBits109,
+			SaveAdd109:    stat.SaveAdd109,
+		}
+	}
+
+	var armor []struct {
+		Code string `txt:"code"`
+	}
+
+	if err := txt.Load(fsys, tableDir+"armor.txt", &armor); err != nil {
+		return nil, err
+	}
+
+	for _, item := range armor {
+		tables.Items[item.Code] = ItemType{Armor: true}
+	}
+
+	for _, name := range []string{"weapons", "misc"} {
+		var items []struct {
+			Code      string `txt:"code"`
+			Stackable bool   `txt:"stackable"`
+		}
+
+		if err := txt.Load(fsys, tableDir+name+".txt", &items); err != nil {
+			return nil, err
+		}
+
+		for _, item := range items {
+			tables.Items[item.Code] = ItemType{Weapon: name == "weapons", Stackable: item.Stackable}
+		}
+	}
+
+	return tables, nil
+}
+
+func (t *Tables) stat(id int) (StatCost, error) {
+	stat, ok := t.Stats[id]
+	if !ok {
+		return StatCost{}, fmt.Errorf("unknown stat %d", id)
+	}
+
+	return stat, nil
+}
+
+func (t *Tables) item(code string) (ItemType, error) {
+	itemType, ok := t.Items[code]
+	if !ok {
+		return ItemType{}, fmt.Errorf("unknown item code %q", code)
+	}
+
+	return itemType, nil
+}
@@ -0,0 +1,126 @@
+package dat
+
+import "github.com/FooSoft/lazarus/math"
+
+type BlendMode int
+
+const (
+	BlendNone BlendMode = iota
+	BlendAlpha25
+	BlendAlpha50
+	BlendAlpha75
+	BlendAdditive
+	BlendMultiply
+	BlendMultiply2X
+)
+
+type ColorOptions struct {
+	OpaqueIndexZero bool
+	Blend           BlendMode
+}
+
+func (p *Palette) ToRGBA(indices []byte, options ColorOptions) []math.Color4b {
+	var colorTable [256]math.Color4b
+	for i, colorSrc := range p.Colors {
+		colorTable[i] = blendColor(colorSrc, options.Blend)
+	}
+
+	if !options.OpaqueIndexZero {
+		colorTable[0] = math.Color4b{}
+	}
+
+	colors := make([]math.Color4b, len(indices))
+	for i, index := range indices {
+		colors[i] = colorTable[index]
+	}
+
+	return colors
+}
+
+func blendColor(color math.Color3b, blend BlendMode) math.Color4b {
+	switch blend {
+	case BlendAlpha25:
+		return math.Color4b{R: color.R, G: color.G, B: color.B, A: 0x40}
+	case BlendAlpha50:
+		return math.Color4b{R: color.R, G: color.G, B: color.B, A: 0x80}
+	case BlendAlpha75:
+		return math.Color4b{R: color.R, G: color.G, B: color.B, A: 0xc0}
+	default:
+		return math.Color4b{R: color.R, G: color.G, B: color.B, A: 0xff}
+	}
+}
+
+func BlendColors(src, dst math.Color4b, blend BlendMode) math.Color4b {
+	if src.A == 0 {
+		return dst
+	}
+
+	switch blend {
+	case BlendAdditive:
+		return math.Color4b{
+			R: byte(minInt(int(dst.R)+int(src.R), 0xff)),
+			G: byte(minInt(int(dst.G)+int(src.G), 0xff)),
+			B: byte(minInt(int(dst.B)+int(src.B), 0xff)),
+			A: dst.A,
+		}
+	case BlendMultiply:
+		return math.Color4b{
+			R: byte(int(dst.R) * int(src.R) / 0xff),
+			G: byte(int(dst.G) * int(src.G) / 0xff),
+			B: byte(int(dst.B) * int(src.B) / 0xff),
+			A: dst.A,
+		}
+	case BlendMultiply2X:
+		return math.Color4b{
+			R: byte(minInt(int(dst.R)*int(src.R)*2/0xff, 0xff)),
+			G: byte(minInt(int(dst.G)*int(src.G)*2/0xff, 0xff)),
+			B: byte(minInt(int(dst.B)*int(src.B)*2/0xff, 0xff)),
+			A: dst.A,
+		}
+	}
+
+	if src.A == 0xff || dst.A == 0 {
+		return src
+	}
+
+	var (
+		srcA = int(src.A)
+		dstA = int(dst.A) * (0xff - srcA) / 0xff
+		outA = srcA + dstA
+	)
+
+	return math.Color4b{
+		R: byte((int(src.R)*srcA + int(dst.R)*dstA) / outA),
+		G: byte((int(src.G)*srcA + int(dst.G)*dstA) / outA),
+		B: byte((int(src.B)*srcA + int(dst.B)*dstA) / outA),
+		A: byte(outA),
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+type Transform [256]byte
+
+func (t *Transform) Apply(indices []byte) []byte {
+	result := make([]byte, len(indices))
+	for i, index := range indices {
+		result[i] = t[index]
+	}
+
+	return result
+}
+
+func (p *Palette) Transform(transform *Transform) *Palette {
+	palette := new(Palette)
+	for i, index := range transform {
+		palette.Colors[i] = p.Colors[index]
+	}
+
+	return palette
+}
@@ -0,0 +1,46 @@
+package dat
+
+import (
+	"testing"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+func TestToRGBA(t *testing.T) {
+	palette := NewFromGrayscale()
+	palette.Colors[1] = math.Color3b{R: 0x40, G: 0x80, B: 0x20}
+
+	test := func(options ColorOptions, expected []math.Color4b) {
+		result := palette.ToRGBA([]byte{0, 1, 0xff}, options)
+		for i := range expected {
+			if result[i] != expected[i] {
+				t.Errorf("color %d (%+v): %+v, expected: %+v", i, options, result[i], expected[i])
+			}
+		}
+	}
+
+	test(ColorOptions{}, []math.Color4b{{}, {R: 0x40, G: 0x80, B: 0x20, A: 0xff}, {R: 0xff, G: 0xff, B: 0xff, A: 0xff}})
+	test(ColorOptions{OpaqueIndexZero: true}, []math.Color4b{{A: 0xff}})
+	test(ColorOptions{Blend: BlendAlpha50}, []math.Color4b{{}, {R: 0x40, G: 0x80, B: 0x20, A: 0x80}})
+	test(ColorOptions{Blend: BlendAdditive}, []math.Color4b{{}, {R: 0x40, G: 0x80, B: 0x20, A: 0xff}})
+}
+
+func TestBlendColors(t *testing.T) {
+	var (
+		dst = math.Color4b{R: 0x80, G: 0xc0, B: 0x10, A: 0xff}
+		src = math.Color4b{R: 0x40, G: 0x80, B: 0xff, A: 0xff}
+	)
+
+	test := func(src, dst math.Color4b, blend BlendMode, expected math.Color4b) {
+		if result := BlendColors(src, dst, blend); result != expected {
+			t.Errorf("blend %d of %+v over %+v: %+v, expected: %+v", blend, src, dst, result, expected)
+		}
+	}
+
+	test(src, dst, BlendNone, src)
+	test(src, dst, BlendAdditive, math.Color4b{R: 0xc0, G: 0xff, B: 0xff, A: 0xff})
+	test(src, dst, BlendMultiply, math.Color4b{R: 0x20, G: 0x60, B: 0x10, A: 0xff})
+	test(src, dst, BlendMultiply2X, math.Color4b{R: 0x40, G: 0xc0, B: 0x20, A: 0xff})
+	test(math.Color4b{R: 0xff, A: 0x80}, math.Color4b{B: 0xff, A: 0xff}, BlendAlpha50, math.Color4b{R: 0x80, B: 0x7f, A: 0xff})
+	test(math.Color4b{}, dst, BlendMultiply, dst)
+}
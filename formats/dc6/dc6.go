@@ -1,31 +1,43 @@
 package dc6
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"io"
 
 	"github.com/FooSoft/lazarus/math"
 	"github.com/FooSoft/lazarus/streaming"
 )
 
+const (
+	fileVersion   = 6
+	fileFlags     = 1
+	fileSkipColor = 0xeeeeeeee
+	frameLineEnd  = 0x80
+	frameMaxRun   = 0x7f
+)
+
+var frameTermination = []byte{0xee, 0xee, 0xee}
+
 type fileHeader struct {
 	Version      uint32
-	_            uint32 // unused: flags
-	_            uint32 // unused: format
-	_            uint32 // unused: skipColor
+	Flags        uint32
+	Format       uint32
+	SkipColor    uint32
 	DirCount     uint32
 	FramesPerDir uint32
 }
 
 type frameHeader struct {
-	Flip    uint32
-	Width   uint32
-	Height  uint32
-	OffsetX int32
-	OffsetY int32
-	_       uint32 // unused: allocSize
-	_       uint32 // unused: nextBlock
-	Length  uint32
+	Flip      uint32
+	Width     uint32
+	Height    uint32
+	OffsetX   int32
+	OffsetY   int32
+	AllocSize uint32
+	NextBlock uint32
+	Length    uint32
 }
 
 type Direction struct {
@@ -35,6 +47,7 @@ type Direction struct {
 type Frame struct {
 	Size   math.Vec2i
 	Offset math.Vec2i
+	Flip   bool
 	Data   []byte
 }
 
@@ -81,7 +94,7 @@ func NewFromReader(reader io.ReadSeeker) (*Animation, error) {
 		var (
 			size      = math.Vec2i{X: int(frameHead.Width), Y: int(frameHead.Height)}
 			offset    = math.Vec2i{X: int(frameHead.OffsetX), Y: int(frameHead.OffsetY)}
-			frame     = Frame{size, offset, data}
+			frame     = Frame{size, offset, frameHead.Flip != 0, data}
 			direction = &sprite.Directions[i/int(fileHead.FramesPerDir)]
 		)
 
@@ -127,3 +140,124 @@ func extractFrame(reader io.ReadSeeker, writer io.WriteSeeker, header frameHeade
 
 	return nil
 }
+
+func (a *Animation) Write(writer io.Writer) error {
+	var framesPerDir int
+	if len(a.Directions) > 0 {
+		framesPerDir = len(a.Directions[0].Frames)
+	}
+
+	var frames []Frame
+	for _, direction := range a.Directions {
+		if len(direction.Frames) != framesPerDir {
+			return errors.New("directions have differing frame counts")
+		}
+
+		frames = append(frames, direction.Frames...)
+	}
+
+	fileHead := fileHeader{
+		Version:      fileVersion,
+		Flags:        fileFlags,
+		SkipColor:    fileSkipColor,
+		DirCount:     uint32(len(a.Directions)),
+		FramesPerDir: uint32(framesPerDir),
+	}
+
+	if err := binary.Write(writer, binary.LittleEndian, fileHead); err != nil {
+		return err
+	}
+
+	var (
+		frameOffset = uint32(binary.Size(fileHead) + len(frames)*4)
+		frameBlocks = make([][]byte, len(frames))
+	)
+
+	for i, frame := range frames {
+		if frame.Size.X < 0 || frame.Size.Y < 0 || len(frame.Data) != frame.Size.X*frame.Size.Y {
+			return errors.New("frame data does not match frame size")
+		}
+
+		frameBlocks[i] = encodeFrame(frame)
+		if err := binary.Write(writer, binary.LittleEndian, frameOffset); err != nil {
+			return err
+		}
+
+		frameOffset += uint32(binary.Size(frameHeader{}) + len(frameBlocks[i]) + len(frameTermination))
+	}
+
+	frameOffset = uint32(binary.Size(fileHead) + len(frames)*4)
+	for i, frame := range frames {
+		frameOffset += uint32(binary.Size(frameHeader{}) + len(frameBlocks[i]) + len(frameTermination))
+
+		frameHead := frameHeader{
+			Width:     uint32(frame.Size.X),
+			Height:    uint32(frame.Size.Y),
+			OffsetX:   int32(frame.Offset.X),
+			OffsetY:   int32(frame.Offset.Y),
+			NextBlock: frameOffset,
+			Length:    uint32(len(frameBlocks[i])),
+		}
+
+		if frame.Flip {
+			frameHead.Flip = 1
+		}
+
+		if err := binary.Write(writer, binary.LittleEndian, frameHead); err != nil {
+			return err
+		}
+
+		if _, err := writer.Write(frameBlocks[i]); err != nil {
+			return err
+		}
+
+		if _, err := writer.Write(frameTermination); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeFrame(frame Frame) []byte {
+	var buff bytes.Buffer
+	for i := 0; i < frame.Size.Y; i++ {
+		y := frame.Size.Y - i - 1
+		if frame.Flip {
+			y = i
+		}
+
+		line := frame.Data[y*frame.Size.X : (y+1)*frame.Size.X]
+		for x := 0; x < len(line); {
+			start := x
+			if line[x] == 0 {
+				for x < len(line) && line[x] == 0 {
+					x++
+				}
+
+				if x == len(line) {
+					break
+				}
+
+				for length := x - start; length > 0; length -= frameMaxRun {
+					if length > frameMaxRun {
+						buff.WriteByte(frameLineEnd | frameMaxRun)
+					} else {
+						buff.WriteByte(frameLineEnd | byte(length))
+					}
+				}
+			} else {
+				for x < len(line) && line[x] != 0 && x-start < frameMaxRun {
+					x++
+				}
+
+				buff.WriteByte(byte(x - start))
+				buff.Write(line[start:x])
+			}
+		}
+
+		buff.WriteByte(frameLineEnd)
+	}
+
+	return buff.Bytes()
+}
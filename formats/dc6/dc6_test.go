@@ -0,0 +1,210 @@
+package dc6
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+var testSprite = []byte{
+	// file header
+	0x06, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xee, 0xee, 0xee, 0xee, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+	// frame pointers
+	0x20, 0x00, 0x00, 0x00, 0x4b, 0x00, 0x00, 0x00,
+	// frame 0 header
+	0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff,
+	0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x4b, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00,
+	// frame 0 data
+	0x01, 0x07, 0x80, 0x81, 0x02, 0x05, 0x06, 0x80, 0xee, 0xee, 0xee,
+	// frame 1 header
+	0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x72, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00,
+	// frame 1 data
+	0x01, 0x01, 0x80, 0x80, 0xee, 0xee, 0xee,
+}
+
+var testDirections = []byte{
+	// file header
+	0x06, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xee, 0xee, 0xee, 0xee, 0x02, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00,
+	// frame pointers
+	0x28, 0x00, 0x00, 0x00, 0x58, 0x00, 0x00, 0x00, 0x83, 0x00, 0x00, 0x00, 0xad, 0x00, 0x00, 0x00,
+	// direction 0 frame 0 header
+	0x00, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0xfe, 0xff, 0xff, 0xff,
+	0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x58, 0x00, 0x00, 0x00, 0x0d, 0x00, 0x00, 0x00,
+	// direction 0 frame 0 data
+	0x80, 0x02, 0x01, 0x02, 0x81, 0x01, 0x03, 0x80, 0x82, 0x02, 0x09, 0x09, 0x80, 0xee, 0xee, 0xee,
+	// direction 0 frame 1 header
+	0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff,
+	0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x83, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00,
+	// direction 0 frame 1 data
+	0x03, 0x06, 0x06, 0x06, 0x80, 0x01, 0x05, 0x80, 0xee, 0xee, 0xee,
+	// direction 1 frame 0 header
+	0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00,
+	0xf9, 0xff, 0xff, 0xff, 0x00, 0x00, 0x00, 0x00, 0xad, 0x00, 0x00, 0x00, 0x07, 0x00, 0x00, 0x00,
+	// direction 1 frame 0 data
+	0x01, 0x08, 0x80, 0x81, 0x01, 0x07, 0x80, 0xee, 0xee, 0xee,
+	// direction 1 frame 1 header
+	0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0xd4, 0xfe, 0xff, 0xff,
+	0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xd4, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00,
+	// direction 1 frame 1 data
+	0x84, 0x01, 0x04, 0x80, 0xee, 0xee, 0xee,
+}
+
+func TestRoundTrip(t *testing.T) {
+	sprite, err := NewFromReader(bytes.NewReader(testSprite))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Frame{
+		{math.Vec2i{X: 3, Y: 2}, math.Vec2i{X: -1, Y: 5}, false, []byte{0, 5, 6, 7, 0, 0}},
+		{math.Vec2i{X: 2, Y: 2}, math.Vec2i{}, true, []byte{1, 0, 0, 0}},
+	}
+
+	if len(sprite.Directions) != 1 || !reflect.DeepEqual(sprite.Directions[0].Frames, expected) {
+		t.Fatalf("decoded frames: %+v, expected: %+v", sprite.Directions, expected)
+	}
+
+	var buff bytes.Buffer
+	if err := sprite.Write(&buff); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buff.Bytes(), testSprite) {
+		t.Errorf("encoded sprite: %x, expected: %x", buff.Bytes(), testSprite)
+	}
+}
+
+func TestDirections(t *testing.T) {
+	sprite, err := NewFromReader(bytes.NewReader(testDirections))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Direction{
+		{Frames: []Frame{
+			{math.Vec2i{X: 4, Y: 3}, math.Vec2i{X: -2, Y: 5}, false, []byte{0, 0, 9, 9, 1, 2, 0, 3, 0, 0, 0, 0}},
+			{math.Vec2i{X: 3, Y: 2}, math.Vec2i{X: -1, Y: 4}, false, []byte{5, 0, 0, 6, 6, 6}},
+		}},
+		{Frames: []Frame{
+			{math.Vec2i{X: 2, Y: 2}, math.Vec2i{X: 3, Y: -7}, false, []byte{0, 7, 8, 0}},
+			{math.Vec2i{X: 5, Y: 1}, math.Vec2i{X: -300, Y: 260}, false, []byte{0, 0, 0, 0, 4}},
+		}},
+	}
+
+	if !reflect.DeepEqual(sprite.Directions, expected) {
+		t.Fatalf("decoded directions: %+v, expected: %+v", sprite.Directions, expected)
+	}
+
+	var buff bytes.Buffer
+	if err := sprite.Write(&buff); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buff.Bytes(), testDirections) {
+		t.Errorf("encoded sprite: %x, expected: %x", buff.Bytes(), testDirections)
+	}
+}
+
+func TestLongRuns(t *testing.T) {
+	var (
+		opaque   []byte
+		expected = make([]byte, 0x90*2)
+	)
+
+	for i := 1; i <= 0x88; i++ {
+		opaque = append(opaque, byte(i))
+		expected[i-1] = byte(i)
+	}
+
+	for i := 0x90 + 0x85; i < len(expected); i++ {
+		expected[i] = 9
+	}
+
+	data := []byte{
+		// file header
+		0x06, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0xee, 0xee, 0xee, 0xee, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+		// frame pointers
+		0x1c, 0x00, 0x00, 0x00,
+		// frame header
+		0x00, 0x00, 0x00, 0x00, 0x90, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xd9, 0x00, 0x00, 0x00, 0x9a, 0x00, 0x00, 0x00,
+		// bottom line
+		0xff, 0x86, 0x0b, 0x09, 0x09, 0x09, 0x09, 0x09, 0x09, 0x09, 0x09, 0x09, 0x09, 0x09, 0x80,
+		// top line
+		0x7f,
+	}
+
+	data = append(data, opaque[:0x7f]...)
+	data = append(data, 0x09)
+	data = append(data, opaque[0x7f:]...)
+	data = append(data, 0x80, 0xee, 0xee, 0xee)
+
+	sprite, err := NewFromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sprite.Directions) != 1 || len(sprite.Directions[0].Frames) != 1 {
+		t.Fatalf("sprite: %+v", sprite)
+	}
+
+	if frame := sprite.Directions[0].Frames[0]; frame.Size != (math.Vec2i{X: 0x90, Y: 2}) || !bytes.Equal(frame.Data, expected) {
+		t.Errorf("decoded frame: %+v, expected data: %v", frame, expected)
+	}
+
+	var buff bytes.Buffer
+	if err := sprite.Write(&buff); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buff.Bytes(), data) {
+		t.Errorf("encoded sprite: %x, expected: %x", buff.Bytes(), data)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	var (
+		random = rand.New(rand.NewSource(1))
+		sprite = &Animation{Directions: make([]Direction, 2)}
+	)
+
+	for i := range sprite.Directions {
+		for j := 0; j < 3; j++ {
+			frame := Frame{
+				Size:   math.Vec2i{X: 300 + j, Y: 20},
+				Offset: math.Vec2i{X: -j, Y: j * 10},
+				Flip:   j%2 == 1,
+				Data:   make([]byte, (300+j)*20),
+			}
+
+			for k := range frame.Data {
+				if random.Intn(4) == 0 || k%300 < 150 {
+					frame.Data[k] = byte(random.Intn(256))
+				}
+			}
+
+			sprite.Directions[i].Frames = append(sprite.Directions[i].Frames, frame)
+		}
+	}
+
+	var buff bytes.Buffer
+	if err := sprite.Write(&buff); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewFromReader(bytes.NewReader(buff.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(result, sprite) {
+		t.Error("decoded sprite does not match encoded sprite")
+	}
+}
@@ -2,10 +2,24 @@ package dcc
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
+
+	"github.com/FooSoft/lazarus/math"
 )
 
+type Direction struct {
+	Frames []Frame
+}
+
+type Frame struct {
+	Size   math.Vec2i
+	Offset math.Vec2i
+	Data   []byte
+}
+
 type Sprite struct {
+	Directions []Direction
 }
 
 type box struct {
@@ -15,6 +29,14 @@ type box struct {
 	y2 int
 }
 
+func (b box) width() int {
+	return b.x2 - b.x1
+}
+
+func (b box) height() int {
+	return b.y2 - b.y1
+}
+
 type fileHeader struct {
 	Signature    uint8
 	Version      uint8
@@ -25,7 +47,9 @@ type fileHeader struct {
 }
 
 type pixelBufferEntry struct {
-	values [4]byte
+	values         [4]byte
+	frameIndex     int
+	frameCellIndex int
 }
 
 func NewFromReader(reader io.ReadSeeker) (*Sprite, error) {
@@ -34,32 +58,47 @@ func NewFromReader(reader io.ReadSeeker) (*Sprite, error) {
 		return nil, err
 	}
 
-	var directions []direction
-	for i := 0; i < int(fileHead.DirCount); i++ {
-		var offsetDir uint32
-		if err := binary.Read(reader, binary.LittleEndian, &offsetDir); err != nil {
-			return nil, err
+	if fileHead.Signature != 0x74 {
+		return nil, errors.New("invalid file signature")
+	}
+
+	dirOffsets := make([]uint32, fileHead.DirCount)
+	if err := binary.Read(reader, binary.LittleEndian, &dirOffsets); err != nil {
+		return nil, err
+	}
+
+	fileSize, err := reader.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	sprite := new(Sprite)
+	for i, dirOffset := range dirOffsets {
+		dirEnd := fileSize
+		if i+1 < len(dirOffsets) {
+			dirEnd = int64(dirOffsets[i+1])
 		}
 
-		offset, err := reader.Seek(0, io.SeekCurrent)
-		if err != nil {
-			return nil, err
+		if int64(dirOffset) > dirEnd {
+			return nil, errors.New("invalid direction offset")
 		}
 
-		if _, err := reader.Seek(int64(offsetDir), io.SeekStart); err != nil {
+		if _, err := reader.Seek(int64(dirOffset), io.SeekStart); err != nil {
 			return nil, err
 		}
 
-		dirData, err := readDirection(reader, fileHead)
-		if err != nil {
+		dirBytes := make([]byte, dirEnd-int64(dirOffset))
+		if _, err := io.ReadFull(reader, dirBytes); err != nil {
 			return nil, err
 		}
-		directions = append(directions, *dirData)
 
-		if _, err := reader.Seek(offset, io.SeekStart); err != nil {
+		dirData, err := readDirection(dirBytes, fileHead)
+		if err != nil {
 			return nil, err
 		}
+
+		sprite.Directions = append(sprite.Directions, dirData.export())
 	}
 
-	return nil, nil
+	return sprite, nil
 }
@@ -0,0 +1,141 @@
+package dcc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/FooSoft/lazarus/math"
+	"github.com/FooSoft/lazarus/streaming"
+)
+
+// buildSprite hand-encodes one direction of four 4x4 frames sharing a single cell:
+// frame 0 uses pixel-code displacements (including a 15 continuation), frame 1
+// uses a pixel mask with raw pixels, frame 2 is an equal cell and frame 3 uses a
+// single raw pixel with 1-bit stage 2 codes.
+func buildSprite() []byte {
+	w := streaming.NewBitWriter()
+
+	w.WriteUint(0, 32)
+	w.WriteBool(true)
+	w.WriteBool(true)
+	w.WriteUint(0, 4)
+	for i := 0; i < 4; i++ {
+		w.WriteUint(3, 4)
+	}
+	w.WriteUint(0, 4)
+	w.WriteUint(0, 4)
+
+	for i := 0; i < 4; i++ {
+		w.WriteUint(4, 4)
+		w.WriteUint(4, 4)
+		w.WriteUint(0, 4)
+		w.WriteUint(3, 4)
+		w.WriteBool(false)
+	}
+
+	w.WriteUint(3, 20)
+	w.WriteUint(8, 20)
+	w.WriteUint(3, 20)
+	w.WriteUint(32, 20)
+
+	for i := 0; i < 256; i++ {
+		w.WriteBool(i < 40 && i%2 == 0)
+	}
+
+	w.WriteUint(0, 1)
+	w.WriteUint(1, 1)
+	w.WriteUint(0, 1)
+
+	w.WriteUint(0x3, 4)
+	w.WriteUint(0x3, 4)
+
+	w.WriteUint(0, 1)
+	w.WriteUint(1, 1)
+	w.WriteUint(1, 1)
+
+	w.WriteUint(5, 8)
+	w.WriteUint(9, 8)
+	w.WriteUint(7, 8)
+	w.WriteUint(7, 8)
+
+	w.WriteUint(1, 4)
+	w.WriteUint(15, 4)
+	w.WriteUint(1, 4)
+	w.WriteUint(0, 4)
+
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			w.WriteUint(uint64((x+y)%3), 2)
+		}
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			w.WriteUint(uint64((x+2*y)%4), 2)
+		}
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			w.WriteUint(uint64((x+y)%2), 1)
+		}
+	}
+
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, fileHeader{Signature: 0x74, Version: 6, DirCount: 1, FramesPerDir: 4, Tag: 1})
+	binary.Write(&buffer, binary.LittleEndian, uint32(19))
+	buffer.Write(w.Bytes())
+
+	return buffer.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	sprite, err := NewFromReader(bytes.NewReader(buildSprite()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sprite.Directions) != 1 || len(sprite.Directions[0].Frames) != 4 {
+		t.Fatalf("sprite: %+v", sprite)
+	}
+
+	frame1 := []byte{
+		18, 10, 0, 0,
+		0, 0, 18, 10,
+		18, 10, 0, 0,
+		0, 0, 18, 10,
+	}
+
+	expected := [][]byte{
+		{
+			34, 2, 0, 34,
+			2, 0, 34, 2,
+			0, 34, 2, 0,
+			34, 2, 0, 34,
+		},
+		frame1,
+		frame1,
+		{
+			14, 0, 14, 0,
+			0, 14, 0, 14,
+			14, 0, 14, 0,
+			0, 14, 0, 14,
+		},
+	}
+
+	for i, frame := range sprite.Directions[0].Frames {
+		if frame.Size != (math.Vec2i{X: 4, Y: 4}) || frame.Offset != (math.Vec2i{X: 0, Y: 3}) {
+			t.Errorf("frame %d bounds: %+v %+v", i, frame.Size, frame.Offset)
+		}
+
+		if !bytes.Equal(frame.Data, expected[i]) {
+			t.Errorf("frame %d data: %v, expected: %v", i, frame.Data, expected[i])
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	data := buildSprite()
+	if _, err := NewFromReader(bytes.NewReader(data[:len(data)-8])); err == nil {
+		t.Error("truncated sprite was accepted")
+	}
+}
@@ -1,8 +1,8 @@
 package dcc
 
 import (
-	"io"
-	"log"
+	"bytes"
+	"errors"
 
 	"github.com/FooSoft/lazarus/streaming"
 )
@@ -29,8 +29,8 @@ func readDirectionHeader(bitReader *streaming.BitReader) (*directionHeader, erro
 	dirHead.Variable0Bits = uint8(bitReader.ReadUint(4))
 	dirHead.WidthBits = uint8(bitReader.ReadUint(4))
 	dirHead.HeightBits = uint8(bitReader.ReadUint(4))
-	dirHead.OffsetXBits = uint8(bitReader.ReadInt(4))
-	dirHead.OffsetYBits = uint8(bitReader.ReadInt(4))
+	dirHead.OffsetXBits = uint8(bitReader.ReadUint(4))
+	dirHead.OffsetYBits = uint8(bitReader.ReadUint(4))
 	dirHead.OptionalBytesBits = uint8(bitReader.ReadUint(4))
 	dirHead.CodedBytesBits = uint8(bitReader.ReadUint(4))
 
@@ -41,8 +41,110 @@ func readDirectionHeader(bitReader *streaming.BitReader) (*directionHeader, erro
 	return &dirHead, nil
 }
 
-func readDirection(reader io.ReadSeeker, fileHead fileHeader) (*direction, error) {
-	bitReader := streaming.NewBitReader(reader)
+type directionStreams struct {
+	equalCell    *streaming.BitReader
+	pixelMask    *streaming.BitReader
+	encodingType *streaming.BitReader
+	rawPixel     *streaming.BitReader
+	pixelCode    *streaming.BitReader
+}
+
+func readDirectionStreams(data []byte, bitReader *streaming.BitReader, dirHead directionHeader) (*directionStreams, [256]byte, error) {
+	var (
+		equalCellSize    int
+		pixelMaskSize    int
+		encodingTypeSize int
+		rawPixelSize     int
+		pixelValues      [256]byte
+	)
+
+	if dirHead.CompressEqualCells {
+		equalCellSize = int(bitReader.ReadUint(20))
+	}
+
+	pixelMaskSize = int(bitReader.ReadUint(20))
+
+	if dirHead.HasRawPixelEncoding {
+		encodingTypeSize = int(bitReader.ReadUint(20))
+		rawPixelSize = int(bitReader.ReadUint(20))
+	}
+
+	var pixelValueCount int
+	for i := 0; i < len(pixelValues); i++ {
+		if bitReader.ReadBool() {
+			pixelValues[pixelValueCount] = byte(i)
+			pixelValueCount++
+		}
+	}
+
+	if err := bitReader.Error(); err != nil {
+		return nil, pixelValues, err
+	}
+
+	var (
+		streams = new(directionStreams)
+		offset  = bitReader.Offset()
+		err     error
+	)
+
+	if equalCellSize > 0 {
+		if streams.equalCell, err = newBitReaderAt(data, offset); err != nil {
+			return nil, pixelValues, err
+		}
+		offset += equalCellSize
+	}
+
+	if streams.pixelMask, err = newBitReaderAt(data, offset); err != nil {
+		return nil, pixelValues, err
+	}
+	offset += pixelMaskSize
+
+	if encodingTypeSize > 0 {
+		if streams.encodingType, err = newBitReaderAt(data, offset); err != nil {
+			return nil, pixelValues, err
+		}
+		offset += encodingTypeSize
+
+		if streams.rawPixel, err = newBitReaderAt(data, offset); err != nil {
+			return nil, pixelValues, err
+		}
+		offset += rawPixelSize
+	}
+
+	if streams.pixelCode, err = newBitReaderAt(data, offset); err != nil {
+		return nil, pixelValues, err
+	}
+
+	return streams, pixelValues, nil
+}
+
+func (s *directionStreams) Error() error {
+	for _, bitReader := range []*streaming.BitReader{s.equalCell, s.pixelMask, s.encodingType, s.rawPixel, s.pixelCode} {
+		if bitReader == nil {
+			continue
+		}
+
+		if err := bitReader.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newBitReaderAt(data []byte, bitOffset int) (*streaming.BitReader, error) {
+	if bitOffset < 0 || bitOffset/8 > len(data) {
+		return nil, errors.New("bitstream offset out of range")
+	}
+
+	bitReader := streaming.NewBitReader(bytes.NewReader(data[bitOffset/8:]))
+	bitReader.ReadUint(bitOffset % 8)
+
+	return bitReader, nil
+}
+
+func readDirection(data []byte, fileHead fileHeader) (*direction, error) {
+	bitReader := streaming.NewBitReader(bytes.NewReader(data))
 
 	dirHead, err := readDirectionHeader(bitReader)
 	if err != nil {
@@ -54,37 +156,224 @@ func readDirection(reader io.ReadSeeker, fileHead fileHeader) (*direction, error
 		return nil, err
 	}
 
-	dirData := direction{bounds: dirBounds}
+	dirData := direction{header: *dirHead, bounds: dirBounds}
 	for _, frameHead := range frameHeads {
 		dirData.frames = append(dirData.frames, newFrame(frameHead, dirBounds))
 	}
 
-	var entries []pixelBufferEntry
-
-	entries, err = dirData.decodeStage1(bitReader, entries)
+	streams, pixelValues, err := readDirectionStreams(data, bitReader, *dirHead)
 	if err != nil {
 		return nil, err
 	}
 
-	entries, err = dirData.decodeStage2(bitReader, entries)
+	dirData.pixelValues = pixelValues
+
+	entries, err := dirData.decodeStage1(streams)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println(dirData.bounds)
+	if err := dirData.decodeStage2(streams.pixelCode, entries); err != nil {
+		return nil, err
+	}
+
 	return &dirData, nil
 }
 
 type direction struct {
-	header directionHeader
-	frames []frame
-	bounds box
+	header      directionHeader
+	frames      []frame
+	bounds      box
+	pixelValues [256]byte
+
+	nbCellsX int
+	nbCellsY int
+}
+
+func (d *direction) decodeStage1(streams *directionStreams) ([]pixelBufferEntry, error) {
+	pixelMaskBitCounts := [16]int{0, 1, 1, 2, 1, 2, 2, 3, 1, 2, 2, 3, 2, 3, 3, 4}
+
+	d.nbCellsX = 1 + (d.bounds.width()-1)/4
+	d.nbCellsY = 1 + (d.bounds.height()-1)/4
+
+	cellBuffer := make([]int, d.nbCellsX*d.nbCellsY)
+	for i := range cellBuffer {
+		cellBuffer[i] = -1
+	}
+
+	var entries []pixelBufferEntry
+	for frameIndex, frameData := range d.frames {
+		var (
+			originCellX = frameData.dirOffsetX / 4
+			originCellY = frameData.dirOffsetY / 4
+		)
+
+		for cellY := 0; cellY < frameData.nbCellsY; cellY++ {
+			for cellX := 0; cellX < frameData.nbCellsX; cellX++ {
+				var (
+					bufferIndex = (originCellY+cellY)*d.nbCellsX + originCellX + cellX
+					pixelMask   = uint64(0x0f)
+				)
+
+				if cellBuffer[bufferIndex] >= 0 {
+					if streams.equalCell != nil && streams.equalCell.ReadBool() {
+						continue
+					}
+
+					pixelMask = streams.pixelMask.ReadUint(4)
+				}
+
+				var (
+					pixelStack   [4]byte
+					pixelCount   int
+					lastPixel    byte
+					encodingType bool
+				)
+
+				pixelBitCount := pixelMaskBitCounts[pixelMask]
+				if pixelBitCount > 0 && streams.encodingType != nil {
+					encodingType = streams.encodingType.ReadBool()
+				}
+
+				for i := 0; i < pixelBitCount; i++ {
+					var pixel uint64
+					if encodingType {
+						pixel = streams.rawPixel.ReadUint(8)
+					} else {
+						pixel = uint64(lastPixel)
+						for {
+							displacement := streams.pixelCode.ReadUint(4)
+							pixel += displacement
+							if displacement != 15 || streams.pixelCode.Error() != nil {
+								break
+							}
+						}
+					}
+
+					if byte(pixel) == lastPixel {
+						break
+					}
+
+					lastPixel = byte(pixel)
+					pixelStack[pixelCount] = lastPixel
+					pixelCount++
+				}
+
+				entry := pixelBufferEntry{frameIndex: frameIndex, frameCellIndex: cellY*frameData.nbCellsX + cellX}
+				for i, stackIndex := 0, pixelCount-1; i < len(entry.values); i++ {
+					if pixelMask&(1<<uint(i)) != 0 {
+						if stackIndex >= 0 {
+							entry.values[i] = pixelStack[stackIndex]
+							stackIndex--
+						}
+					} else {
+						entry.values[i] = entries[cellBuffer[bufferIndex]].values[i]
+					}
+				}
+
+				cellBuffer[bufferIndex] = len(entries)
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	if err := streams.Error(); err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		for j, value := range entries[i].values {
+			entries[i].values[j] = d.pixelValues[value]
+		}
+	}
+
+	return entries, nil
 }
 
-func (d *direction) decodeStage1(bitReader *streaming.BitReader, entries []pixelBufferEntry) ([]pixelBufferEntry, error) {
-	return nil, nil
+func (d *direction) decodeStage2(bitReader *streaming.BitReader, entries []pixelBufferEntry) error {
+	var (
+		dirWidth  = d.bounds.width()
+		dirPixels = make([]byte, dirWidth*d.bounds.height())
+		dirCells  = make([]cell, d.nbCellsX*d.nbCellsY)
+	)
+
+	for i := range dirCells {
+		dirCells[i].lastWidth = -1
+		dirCells[i].lastHeight = -1
+	}
+
+	var entryIndex int
+	for frameIndex := range d.frames {
+		frameData := &d.frames[frameIndex]
+		frameWidth := int(frameData.header.Width)
+
+		for cellIndex, cellData := range frameData.cells {
+			dirCell := &dirCells[(cellData.y/4)*d.nbCellsX+cellData.x/4]
+
+			if entryIndex >= len(entries) || entries[entryIndex].frameIndex != frameIndex || entries[entryIndex].frameCellIndex != cellIndex {
+				if cellData.width != dirCell.lastWidth || cellData.height != dirCell.lastHeight {
+					for y := 0; y < cellData.height; y++ {
+						for x := 0; x < cellData.width; x++ {
+							dirPixels[(cellData.y+y)*dirWidth+cellData.x+x] = 0
+						}
+					}
+				} else {
+					pixels := make([]byte, cellData.width*cellData.height)
+					for y := 0; y < cellData.height; y++ {
+						copy(pixels[y*cellData.width:], dirPixels[(dirCell.lastY+y)*dirWidth+dirCell.lastX:][:cellData.width])
+					}
+					for y := 0; y < cellData.height; y++ {
+						copy(dirPixels[(cellData.y+y)*dirWidth+cellData.x:], pixels[y*cellData.width:][:cellData.width])
+					}
+				}
+			} else {
+				entry := entries[entryIndex]
+				if entry.values[0] == entry.values[1] {
+					for y := 0; y < cellData.height; y++ {
+						for x := 0; x < cellData.width; x++ {
+							dirPixels[(cellData.y+y)*dirWidth+cellData.x+x] = entry.values[0]
+						}
+					}
+				} else {
+					bitCount := 1
+					if entry.values[1] != entry.values[2] {
+						bitCount = 2
+					}
+
+					for y := 0; y < cellData.height; y++ {
+						for x := 0; x < cellData.width; x++ {
+							dirPixels[(cellData.y+y)*dirWidth+cellData.x+x] = entry.values[bitReader.ReadUint(bitCount)]
+						}
+					}
+				}
+
+				entryIndex++
+			}
+
+			for y := 0; y < cellData.height; y++ {
+				var (
+					dirOffset   = (cellData.y+y)*dirWidth + cellData.x
+					frameOffset = (cellData.y-frameData.dirOffsetY+y)*frameWidth + cellData.x - frameData.dirOffsetX
+				)
+
+				copy(frameData.data[frameOffset:], dirPixels[dirOffset:dirOffset+cellData.width])
+			}
+
+			dirCell.lastX = cellData.x
+			dirCell.lastY = cellData.y
+			dirCell.lastWidth = cellData.width
+			dirCell.lastHeight = cellData.height
+		}
+	}
+
+	return bitReader.Error()
 }
 
-func (d *direction) decodeStage2(bitReader *streaming.BitReader, entries []pixelBufferEntry) ([]pixelBufferEntry, error) {
-	return nil, nil
+func (d *direction) export() Direction {
+	var dirData Direction
+	for i := range d.frames {
+		dirData.Frames = append(dirData.Frames, d.frames[i].export())
+	}
+
+	return dirData
 }
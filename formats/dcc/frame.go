@@ -3,6 +3,7 @@ package dcc
 import (
 	"errors"
 
+	"github.com/FooSoft/lazarus/math"
 	"github.com/FooSoft/lazarus/streaming"
 )
 
@@ -93,6 +94,18 @@ func readFrameHeaders(bitReader *streaming.BitReader, fileHead fileHeader, dirHe
 	return frameHeads, boundsAll, nil
 }
 
+type cell struct {
+	x      int
+	y      int
+	width  int
+	height int
+
+	lastX      int
+	lastY      int
+	lastWidth  int
+	lastHeight int
+}
+
 type frame struct {
 	header frameHeader
 
@@ -101,9 +114,9 @@ type frame struct {
 	dirOffsetX int
 	dirOffsetY int
 
-	cellSameAsPrevious []bool
-	cellWidths         []int
-	cellHeights        []int
+	cells       []cell
+	cellWidths  []int
+	cellHeights []int
 
 	data []byte
 }
@@ -169,9 +182,33 @@ func newFrame(frameHead frameHeader, dirBounds box) frame {
 		frameData.cellHeights[frameData.nbCellsY-1] = frameHeight - (heightFirstRow + heightExcludingFirstAndLastRows)
 	}
 
-	frameData.cellSameAsPrevious = make([]bool, frameData.nbCellsX*frameData.nbCellsY)
+	frameData.cells = make([]cell, frameData.nbCellsX*frameData.nbCellsY)
+	cellY := frameData.dirOffsetY
+	for y := 0; y < frameData.nbCellsY; y++ {
+		cellX := frameData.dirOffsetX
+		for x := 0; x < frameData.nbCellsX; x++ {
+			frameData.cells[y*frameData.nbCellsX+x] = cell{
+				x:      cellX,
+				y:      cellY,
+				width:  frameData.cellWidths[x],
+				height: frameData.cellHeights[y],
+			}
+
+			cellX += frameData.cellWidths[x]
+		}
+
+		cellY += frameData.cellHeights[y]
+	}
+
 	frameData.data = make([]byte, frameWidth*frameHeight)
 
 	return frameData
+}
 
+func (f *frame) export() Frame {
+	return Frame{
+		Size:   math.Vec2i{X: int(f.header.Width), Y: int(f.header.Height)},
+		Offset: math.Vec2i{X: int(f.header.OffsetX), Y: int(f.header.OffsetY)},
+		Data:   f.data,
+	}
 }
@@ -0,0 +1,440 @@
+package ds1
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+const (
+	fileVersionMin = 1
+	fileVersionMax = 18
+)
+
+const (
+	SubstitutionNone = 0
+	SubstitutionA    = 1
+	SubstitutionB    = 2
+)
+
+var orientationLookup = []int{
+	0x00, 0x01, 0x02, 0x01, 0x02, 0x03, 0x03, 0x05, 0x05, 0x06, 0x06, 0x07, 0x07,
+	0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11, 0x12, 0x14,
+}
+
+type layerType int
+
+const (
+	layerWall layerType = iota
+	layerOrientation
+	layerFloor
+	layerShadow
+	layerSubstitution
+)
+
+type layer struct {
+	kind  layerType
+	index int
+}
+
+type Tile struct {
+	Prop1     byte
+	SubIndex  byte
+	Unknown1  byte
+	MainIndex byte
+	Unknown2  byte
+	Hidden    bool
+}
+
+type WallTile struct {
+	Tile
+	Orientation int
+	Zero        uint32
+}
+
+type PathPoint struct {
+	Position math.Vec2i
+	Action   int
+}
+
+type Object struct {
+	Type     int
+	Id       int
+	Position math.Vec2i
+	Flags    int
+	Path     []PathPoint
+}
+
+type SubstitutionGroup struct {
+	Position math.Vec2i
+	Size     math.Vec2i
+	Unknown  int
+}
+
+type Map struct {
+	Version            int
+	Size               math.Vec2i
+	Act                int
+	SubstitutionType   int
+	Files              []string
+	Walls              [][]WallTile
+	Floors             [][]Tile
+	Shadows            [][]Tile
+	Substitutions      [][]uint32
+	Objects            []Object
+	SubstitutionGroups []SubstitutionGroup
+}
+
+func NewFromReader(reader io.Reader) (*Map, error) {
+	var version int32
+	if err := binary.Read(reader, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+
+	if version < fileVersionMin || version > fileVersionMax {
+		return nil, errors.New("unsupported file version")
+	}
+
+	m := &Map{Version: int(version)}
+
+	var size [2]int32
+	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+
+	m.Size = math.Vec2i{X: int(size[0]) + 1, Y: int(size[1]) + 1}
+	if m.Size.X <= 0 || m.Size.Y <= 0 {
+		return nil, errors.New("invalid map size")
+	}
+
+	if m.Version >= 8 {
+		value, err := readInt(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		m.Act = value
+	}
+
+	if m.Version >= 10 {
+		value, err := readInt(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		m.SubstitutionType = value
+	}
+
+	if m.Version >= 3 {
+		if err := m.readFiles(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.Version >= 9 && m.Version <= 13 {
+		var unknown [2]int32
+		if err := binary.Read(reader, binary.LittleEndian, &unknown); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := m.readLayers(reader); err != nil {
+		return nil, err
+	}
+
+	if m.Version >= 2 {
+		if err := m.readObjects(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.Version >= 12 && m.hasSubstitutions() {
+		if err := m.readSubstitutionGroups(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.Version >= 14 {
+		if err := m.readPaths(reader); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Map) hasSubstitutions() bool {
+	return m.SubstitutionType == SubstitutionA || m.SubstitutionType == SubstitutionB
+}
+
+func (m *Map) readFiles(reader io.Reader) error {
+	count, err := readInt(reader)
+	if err != nil {
+		return err
+	}
+
+	if count < 0 {
+		return errors.New("invalid file count")
+	}
+
+	for i := 0; i < count; i++ {
+		var (
+			path []byte
+			char [1]byte
+		)
+
+		for {
+			if _, err := io.ReadFull(reader, char[:]); err != nil {
+				return err
+			}
+
+			if char[0] == 0 {
+				break
+			}
+
+			path = append(path, char[0])
+		}
+
+		m.Files = append(m.Files, string(path))
+	}
+
+	return nil
+}
+
+func (m *Map) readLayers(reader io.Reader) error {
+	var layers []layer
+	if m.Version < 4 {
+		m.Walls = make([][]WallTile, 1)
+		m.Floors = make([][]Tile, 1)
+		m.Shadows = make([][]Tile, 1)
+		m.Substitutions = make([][]uint32, 1)
+		layers = []layer{{layerWall, 0}, {layerFloor, 0}, {layerOrientation, 0}, {layerSubstitution, 0}, {layerShadow, 0}}
+	} else {
+		wallCount, err := readInt(reader)
+		if err != nil {
+			return err
+		}
+
+		floorCount := 1
+		if m.Version >= 16 {
+			if floorCount, err = readInt(reader); err != nil {
+				return err
+			}
+		}
+
+		if wallCount < 0 || floorCount < 0 {
+			return errors.New("invalid layer count")
+		}
+
+		m.Walls = make([][]WallTile, wallCount)
+		m.Floors = make([][]Tile, floorCount)
+		m.Shadows = make([][]Tile, 1)
+
+		for i := 0; i < wallCount; i++ {
+			layers = append(layers, layer{layerWall, i}, layer{layerOrientation, i})
+		}
+		for i := 0; i < floorCount; i++ {
+			layers = append(layers, layer{layerFloor, i})
+		}
+
+		layers = append(layers, layer{layerShadow, 0})
+		if m.hasSubstitutions() {
+			m.Substitutions = make([][]uint32, 1)
+			layers = append(layers, layer{layerSubstitution, 0})
+		}
+	}
+
+	cellCount := m.Size.X * m.Size.Y
+	for i := range m.Walls {
+		m.Walls[i] = make([]WallTile, cellCount)
+	}
+	for i := range m.Floors {
+		m.Floors[i] = make([]Tile, cellCount)
+	}
+	for i := range m.Shadows {
+		m.Shadows[i] = make([]Tile, cellCount)
+	}
+
+	values := make([]uint32, cellCount)
+	for _, l := range layers {
+		if err := binary.Read(reader, binary.LittleEndian, values); err != nil {
+			return err
+		}
+
+		switch l.kind {
+		case layerWall:
+			for j, value := range values {
+				m.Walls[l.index][j].Tile = decodeTile(value)
+			}
+		case layerOrientation:
+			for j, value := range values {
+				orientation := int(value & 0xff)
+				if m.Version < 7 && orientation < len(orientationLookup) {
+					orientation = orientationLookup[orientation]
+				}
+
+				m.Walls[l.index][j].Orientation = orientation
+				m.Walls[l.index][j].Zero = value >> 8
+			}
+		case layerFloor:
+			for j, value := range values {
+				m.Floors[l.index][j] = decodeTile(value)
+			}
+		case layerShadow:
+			for j, value := range values {
+				m.Shadows[l.index][j] = decodeTile(value)
+			}
+		case layerSubstitution:
+			m.Substitutions[l.index] = append([]uint32(nil), values...)
+		}
+	}
+
+	return nil
+}
+
+func (m *Map) readObjects(reader io.Reader) error {
+	count, err := readInt(reader)
+	if err != nil {
+		return err
+	}
+
+	if count < 0 {
+		return errors.New("invalid object count")
+	}
+
+	fieldCount := 4
+	if m.Version > 5 {
+		fieldCount = 5
+	}
+
+	m.Objects = make([]Object, count)
+	for i := range m.Objects {
+		fields := make([]int32, fieldCount)
+		if err := binary.Read(reader, binary.LittleEndian, fields); err != nil {
+			return err
+		}
+
+		object := Object{
+			Type:     int(fields[0]),
+			Id:       int(fields[1]),
+			Position: math.Vec2i{X: int(fields[2]), Y: int(fields[3])},
+		}
+
+		if fieldCount > 4 {
+			object.Flags = int(fields[4])
+		}
+
+		m.Objects[i] = object
+	}
+
+	return nil
+}
+
+func (m *Map) readSubstitutionGroups(reader io.Reader) error {
+	if m.Version >= 18 {
+		if _, err := readInt(reader); err != nil {
+			return err
+		}
+	}
+
+	count, err := readInt(reader)
+	if err != nil {
+		return err
+	}
+
+	if count < 0 {
+		return errors.New("invalid substitution group count")
+	}
+
+	m.SubstitutionGroups = make([]SubstitutionGroup, count)
+	for i := range m.SubstitutionGroups {
+		var fields [5]int32
+		if err := binary.Read(reader, binary.LittleEndian, &fields); err != nil {
+			return err
+		}
+
+		m.SubstitutionGroups[i] = SubstitutionGroup{
+			Position: math.Vec2i{X: int(fields[0]), Y: int(fields[1])},
+			Size:     math.Vec2i{X: int(fields[2]), Y: int(fields[3])},
+			Unknown:  int(fields[4]),
+		}
+	}
+
+	return nil
+}
+
+func (m *Map) readPaths(reader io.Reader) error {
+	count, err := readInt(reader)
+	if err != nil {
+		return err
+	}
+
+	if count < 0 {
+		return errors.New("invalid path count")
+	}
+
+	fieldCount := 2
+	if m.Version >= 15 {
+		fieldCount = 3
+	}
+
+	for i := 0; i < count; i++ {
+		var header [3]int32
+		if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+			return err
+		}
+
+		if header[0] < 0 {
+			return errors.New("invalid path point count")
+		}
+
+		path := make([]PathPoint, header[0])
+		for j := range path {
+			fields := make([]int32, fieldCount)
+			if err := binary.Read(reader, binary.LittleEndian, fields); err != nil {
+				return err
+			}
+
+			path[j] = PathPoint{Position: math.Vec2i{X: int(fields[0]), Y: int(fields[1])}, Action: 1}
+			if fieldCount > 2 {
+				path[j].Action = int(fields[2])
+			}
+		}
+
+		position := math.Vec2i{X: int(header[1]), Y: int(header[2])}
+		for j := range m.Objects {
+			if object := &m.Objects[j]; object.Position == position {
+				object.Path = path
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func (m *Map) Cell(x, y int) int {
+	return y*m.Size.X + x
+}
+
+func decodeTile(value uint32) Tile {
+	return Tile{
+		Prop1:     byte(value),
+		SubIndex:  byte(value >> 8 & 0x3f),
+		Unknown1:  byte(value >> 14 & 0x3f),
+		MainIndex: byte(value >> 20 & 0x3f),
+		Unknown2:  byte(value >> 26 & 0x1f),
+		Hidden:    value&0x80000000 != 0,
+	}
+}
+
+func readInt(reader io.Reader) (int, error) {
+	var value int32
+	if err := binary.Read(reader, binary.LittleEndian, &value); err != nil {
+		return 0, err
+	}
+
+	return int(value), nil
+}
@@ -0,0 +1,384 @@
+package ds1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+type testWriter struct {
+	bytes.Buffer
+}
+
+func (w *testWriter) int(values ...int) {
+	for _, value := range values {
+		binary.Write(w, binary.LittleEndian, int32(value))
+	}
+}
+
+func (w *testWriter) layer(base uint32, count int) {
+	for i := 0; i < count; i++ {
+		binary.Write(w, binary.LittleEndian, base+uint32(i))
+	}
+}
+
+func buildMap(version int) []byte {
+	var (
+		w                = new(testWriter)
+		cellCount        = 3 * 2
+		substitutionType = SubstitutionA
+	)
+
+	w.int(version, 2, 1)
+	if version >= 8 {
+		w.int(2)
+	}
+	if version >= 10 {
+		w.int(substitutionType)
+	}
+	if version >= 3 {
+		w.int(2)
+		w.WriteString("data\\global\\tiles\\act1\\town\\floor.dt1\x00")
+		w.WriteString("data\\global\\tiles\\act1\\town\\fence.dt1\x00")
+	}
+	if version >= 9 && version <= 13 {
+		w.int(0, 0)
+	}
+
+	if version < 4 {
+		w.layer(0x80300201, cellCount)
+		w.layer(0x00100100, cellCount)
+		w.layer(0x00000003, cellCount)
+		w.layer(0x12345678, cellCount)
+		w.layer(0x00000000, cellCount)
+	} else {
+		w.int(2)
+		if version >= 16 {
+			w.int(2)
+		}
+
+		w.layer(0x80300201, cellCount)
+		w.layer(0x00000003, cellCount)
+		w.layer(0x00400300, cellCount)
+		w.layer(0x00000104, cellCount)
+		w.layer(0x00100100, cellCount)
+		if version >= 16 {
+			w.layer(0x00200200, cellCount)
+		}
+		w.layer(0x00000000, cellCount)
+		if version >= 10 {
+			w.layer(0x12345678, cellCount)
+		}
+	}
+
+	if version >= 2 {
+		w.int(2)
+		w.int(1, 7, 10, 20)
+		if version > 5 {
+			w.int(4)
+		}
+		w.int(2, 9, 30, 40)
+		if version > 5 {
+			w.int(0)
+		}
+	}
+
+	if version >= 12 {
+		if version >= 18 {
+			w.int(0)
+		}
+		w.int(1)
+		w.int(1, 0, 2, 1, -1)
+	}
+
+	if version >= 14 {
+		w.int(1, 2, 10, 20)
+		w.int(11, 21)
+		if version >= 15 {
+			w.int(3)
+		}
+		w.int(12, 22)
+		if version >= 15 {
+			w.int(1)
+		}
+	}
+
+	return w.Bytes()
+}
+
+func TestNewFromReader(t *testing.T) {
+	for version := fileVersionMin; version <= fileVersionMax; version++ {
+		m, err := NewFromReader(bytes.NewReader(buildMap(version)))
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+
+		if m.Version != version || m.Size != (math.Vec2i{X: 3, Y: 2}) {
+			t.Errorf("version %d: header: %+v", version, m)
+		}
+
+		if version >= 8 && m.Act != 2 {
+			t.Errorf("version %d: act: %d", version, m.Act)
+		}
+
+		if version >= 3 && (len(m.Files) != 2 || m.Files[1] != "data\\global\\tiles\\act1\\town\\fence.dt1") {
+			t.Errorf("version %d: files: %v", version, m.Files)
+		}
+
+		wallCount, floorCount := 2, 1
+		if version < 4 {
+			wallCount = 1
+		}
+		if version >= 16 {
+			floorCount = 2
+		}
+
+		if len(m.Walls) != wallCount || len(m.Floors) != floorCount || len(m.Shadows) != 1 {
+			t.Fatalf("version %d: layer counts: %d, %d, %d", version, len(m.Walls), len(m.Floors), len(m.Shadows))
+		}
+
+		wall := m.Walls[0][m.Cell(1, 1)]
+		expectedWall := WallTile{
+			Tile:        Tile{Prop1: 0x05, SubIndex: 0x02, MainIndex: 0x03, Hidden: true},
+			Orientation: 0x07,
+		}
+		if version < 7 {
+			expectedWall.Orientation = 0x05
+		}
+		if wall != expectedWall {
+			t.Errorf("version %d: wall: %+v, expected: %+v", version, wall, expectedWall)
+		}
+
+		if floor := m.Floors[0][m.Cell(2, 0)]; floor != (Tile{Prop1: 0x02, SubIndex: 0x01, MainIndex: 0x01}) {
+			t.Errorf("version %d: floor: %+v", version, floor)
+		}
+
+		if version >= 4 {
+			expectedOrientation := 0x04
+			if version < 7 {
+				expectedOrientation = 0x02
+			}
+
+			if wall := m.Walls[1][0]; wall.MainIndex != 0x04 || wall.SubIndex != 0x03 || wall.Orientation != expectedOrientation || wall.Zero != 0x01 {
+				t.Errorf("version %d: second wall: %+v", version, wall)
+			}
+		}
+
+		hasSubstitutions := version < 4 || version >= 10
+		if hasSubstitutions != (len(m.Substitutions) == 1) || hasSubstitutions && m.Substitutions[0][5] != 0x1234567d {
+			t.Errorf("version %d: substitutions: %v", version, m.Substitutions)
+		}
+
+		var expectedObjects []Object
+		if version >= 2 {
+			expectedObjects = []Object{
+				{Type: 1, Id: 7, Position: math.Vec2i{X: 10, Y: 20}},
+				{Type: 2, Id: 9, Position: math.Vec2i{X: 30, Y: 40}},
+			}
+
+			if version > 5 {
+				expectedObjects[0].Flags = 4
+			}
+
+			if version >= 14 {
+				expectedObjects[0].Path = []PathPoint{
+					{Position: math.Vec2i{X: 11, Y: 21}, Action: 1},
+					{Position: math.Vec2i{X: 12, Y: 22}, Action: 1},
+				}
+
+				if version >= 15 {
+					expectedObjects[0].Path[0].Action = 3
+				}
+			}
+		}
+
+		if len(m.Objects) != len(expectedObjects) || len(expectedObjects) > 0 && !reflect.DeepEqual(m.Objects, expectedObjects) {
+			t.Errorf("version %d: objects: %+v, expected: %+v", version, m.Objects, expectedObjects)
+		}
+
+		var expectedGroups []SubstitutionGroup
+		if version >= 12 {
+			expectedGroups = []SubstitutionGroup{{Position: math.Vec2i{X: 1}, Size: math.Vec2i{X: 2, Y: 1}, Unknown: -1}}
+		}
+
+		if len(m.SubstitutionGroups) != len(expectedGroups) || len(expectedGroups) > 0 && !reflect.DeepEqual(m.SubstitutionGroups, expectedGroups) {
+			t.Errorf("version %d: substitution groups: %+v", version, m.SubstitutionGroups)
+		}
+	}
+}
+
+func TestInvalidVersion(t *testing.T) {
+	data := buildMap(fileVersionMax)
+	data[0] = fileVersionMax + 1
+
+	if _, err := NewFromReader(bytes.NewReader(data)); err == nil {
+		t.Error("invalid version was accepted")
+	}
+
+	if _, err := NewFromReader(bytes.NewReader(buildMap(fileVersionMax)[:100])); err == nil {
+		t.Error("truncated file was accepted")
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	fixtures := []struct {
+		data     []byte
+		expected *Map
+	}{
+		{
+			[]byte{
+				// header
+				0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				// files
+				0x01, 0x00, 0x00, 0x00, 0x61, 0x2e, 0x64, 0x74, 0x31, 0x00,
+				// layers
+				0x01, 0x02, 0x30, 0x80, 0x05, 0x01, 0x10, 0x00, 0x03, 0x00, 0x00, 0x00, 0xaa, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00,
+				// objects
+				0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00,
+				0x04, 0x00, 0x00, 0x00,
+			},
+			&Map{
+				Version:       3,
+				Size:          math.Vec2i{X: 1, Y: 1},
+				Files:         []string{"a.dt1"},
+				Walls:         [][]WallTile{{{Tile: Tile{Prop1: 1, SubIndex: 2, MainIndex: 3, Hidden: true}, Orientation: 1}}},
+				Floors:        [][]Tile{{{Prop1: 5, SubIndex: 1, MainIndex: 1}}},
+				Shadows:       [][]Tile{{{}}},
+				Substitutions: [][]uint32{{0xaa}},
+				Objects:       []Object{{Type: 1, Id: 2, Position: math.Vec2i{X: 3, Y: 4}}},
+			},
+		},
+		{
+			[]byte{
+				// header
+				0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				// files
+				0x01, 0x00, 0x00, 0x00, 0x62, 0x2e, 0x64, 0x74, 0x31, 0x00,
+				// layers
+				0x01, 0x00, 0x00, 0x00, 0x02, 0x04, 0x50, 0x00, 0x03, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x07, 0x00, 0x00, 0x00,
+				// objects
+				0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00,
+				0x07, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00,
+			},
+			&Map{
+				Version: 7,
+				Size:    math.Vec2i{X: 1, Y: 1},
+				Files:   []string{"b.dt1"},
+				Walls:   [][]WallTile{{{Tile: Tile{Prop1: 2, SubIndex: 4, MainIndex: 5}, Orientation: 3, Zero: 1}}},
+				Floors:  [][]Tile{{{}}},
+				Shadows: [][]Tile{{{Prop1: 7}}},
+				Objects: []Object{{Type: 2, Id: 5, Position: math.Vec2i{X: 6, Y: 7}, Flags: 8}},
+			},
+		},
+		{
+			[]byte{
+				// header
+				0x0c, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00,
+				0x01, 0x00, 0x00, 0x00,
+				// files
+				0x00, 0x00, 0x00, 0x00,
+				// unknown
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				// layers
+				0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0e, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x80,
+				0x00, 0x00, 0x00, 0x00, 0x44, 0x33, 0x22, 0x11,
+				// objects
+				0x00, 0x00, 0x00, 0x00,
+				// substitution groups
+				0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00,
+				0x04, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00,
+			},
+			&Map{
+				Version:            12,
+				Size:               math.Vec2i{X: 1, Y: 1},
+				Act:                3,
+				SubstitutionType:   SubstitutionA,
+				Walls:              [][]WallTile{{{Orientation: 14}}},
+				Floors:             [][]Tile{{{Prop1: 0x0a, Hidden: true}}},
+				Shadows:            [][]Tile{{{}}},
+				Substitutions:      [][]uint32{{0x11223344}},
+				Objects:            []Object{},
+				SubstitutionGroups: []SubstitutionGroup{{Position: math.Vec2i{X: 1, Y: 2}, Size: math.Vec2i{X: 3, Y: 4}, Unknown: 5}},
+			},
+		},
+		{
+			[]byte{
+				// header
+				0x0f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00,
+				// files
+				0x01, 0x00, 0x00, 0x00, 0x63, 0x2e, 0x64, 0x74, 0x31, 0x00,
+				// layers
+				0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				// objects
+				0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x0a, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00,
+				0x06, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				// paths
+				0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00,
+				0x07, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00,
+				0x0a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			},
+			&Map{
+				Version: 15,
+				Size:    math.Vec2i{X: 1, Y: 1},
+				Act:     1,
+				Files:   []string{"c.dt1"},
+				Walls:   [][]WallTile{},
+				Floors:  [][]Tile{{{Prop1: 1}}},
+				Shadows: [][]Tile{{{}}},
+				Objects: []Object{{
+					Type:     1,
+					Id:       10,
+					Position: math.Vec2i{X: 5, Y: 6},
+					Path:     []PathPoint{{Position: math.Vec2i{X: 7, Y: 8}, Action: 2}, {Position: math.Vec2i{X: 9, Y: 10}}},
+				}},
+			},
+		},
+		{
+			[]byte{
+				// header
+				0x12, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00,
+				0x02, 0x00, 0x00, 0x00,
+				// files
+				0x00, 0x00, 0x00, 0x00,
+				// layers
+				0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				0x00, 0x01, 0x10, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+				// objects
+				0x00, 0x00, 0x00, 0x00,
+				// substitution groups
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+				// paths
+				0x00, 0x00, 0x00, 0x00,
+			},
+			&Map{
+				Version:            18,
+				Size:               math.Vec2i{X: 1, Y: 1},
+				Act:                5,
+				SubstitutionType:   SubstitutionB,
+				Walls:              [][]WallTile{{{}}},
+				Floors:             [][]Tile{{{SubIndex: 1, MainIndex: 1}}, {{Prop1: 3}}},
+				Shadows:            [][]Tile{{{}}},
+				Substitutions:      [][]uint32{{1}},
+				Objects:            []Object{},
+				SubstitutionGroups: []SubstitutionGroup{},
+			},
+		},
+	}
+
+	for _, fixture := range fixtures {
+		m, err := NewFromReader(bytes.NewReader(fixture.data))
+		if err != nil {
+			t.Fatalf("version %d: %v", fixture.expected.Version, err)
+		}
+
+		if !reflect.DeepEqual(m, fixture.expected) {
+			t.Errorf("version %d: map: %+v, expected: %+v", fixture.expected.Version, m, fixture.expected)
+		}
+	}
+}
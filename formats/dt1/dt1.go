@@ -0,0 +1,280 @@
+package dt1
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+const (
+	fileVersionMajor = 7
+	fileVersionMinor = 6
+)
+
+const (
+	OrientationFloor            = 0
+	OrientationLeftWall         = 1
+	OrientationRightWall        = 2
+	OrientationNorthCornerRight = 3
+	OrientationNorthCornerLeft  = 4
+	OrientationLeftEndWall      = 5
+	OrientationRightEndWall     = 6
+	OrientationSouthCorner      = 7
+	OrientationLeftWallDoor     = 8
+	OrientationRightWallDoor    = 9
+	OrientationSpecial1         = 10
+	OrientationSpecial2         = 11
+	OrientationPillar           = 12
+	OrientationShadow           = 13
+	OrientationTree             = 14
+	OrientationRoof             = 15
+	OrientationLowerLeftWall    = 16
+	OrientationLowerRightWall   = 17
+	OrientationLowerNorthCorner = 18
+	OrientationLowerSouthCorner = 19
+)
+
+type SubtileFlags byte
+
+const (
+	SubtileBlockWalk       SubtileFlags = 0x01
+	SubtileBlockLightSight SubtileFlags = 0x02
+	SubtileBlockJump       SubtileFlags = 0x04
+	SubtileBlockPlayerWalk SubtileFlags = 0x08
+	SubtileBlockLight      SubtileFlags = 0x20
+)
+
+type BlockFormat int
+
+const (
+	BlockRle       BlockFormat = 0
+	BlockIsometric BlockFormat = 1
+)
+
+const (
+	blockWidth           = 32
+	blockHeight          = 32
+	blockIsometricHeight = 15
+	blockIsometricSize   = 256
+	subtileCount         = 5
+)
+
+var (
+	isometricOffsets = [blockIsometricHeight]int{14, 12, 10, 8, 6, 4, 2, 0, 2, 4, 6, 8, 10, 12, 14}
+	isometricLengths = [blockIsometricHeight]int{4, 8, 12, 16, 20, 24, 28, 32, 28, 24, 20, 16, 12, 8, 4}
+)
+
+type fileHeader struct {
+	VersionMajor uint32
+	VersionMinor uint32
+	_            [260]byte
+	TileCount    uint32
+	TilePos      uint32
+}
+
+type tileHeader struct {
+	Direction     int32
+	RoofHeight    int16
+	MaterialFlags uint16
+	Height        int32
+	Width         int32
+	_             uint32
+	Orientation   int32
+	MainIndex     int32
+	SubIndex      int32
+	Rarity        int32
+	_             uint32
+	SubtileFlags  [subtileCount * subtileCount]byte
+	_             [7]byte
+	BlockPos      uint32
+	BlockSize     uint32
+	BlockCount    uint32
+	_             [12]byte
+}
+
+type blockHeader struct {
+	X          int16
+	Y          int16
+	_          uint16
+	GridX      uint8
+	GridY      uint8
+	Format     int16
+	Length     uint32
+	_          uint16
+	DataOffset uint32
+}
+
+type Block struct {
+	Size   math.Vec2i
+	Offset math.Vec2i
+	Grid   math.Vec2i
+	Format BlockFormat
+	Data   []byte
+}
+
+type Tile struct {
+	Size          math.Vec2i
+	Direction     int
+	RoofHeight    int
+	MaterialFlags int
+	Orientation   int
+	MainIndex     int
+	SubIndex      int
+	Rarity        int
+	SubtileFlags  [subtileCount * subtileCount]SubtileFlags
+	Blocks        []Block
+}
+
+type TileSet struct {
+	Tiles []Tile
+}
+
+func NewFromReader(reader io.ReadSeeker) (*TileSet, error) {
+	var fileHead fileHeader
+	if err := binary.Read(reader, binary.LittleEndian, &fileHead); err != nil {
+		return nil, err
+	}
+
+	if fileHead.VersionMajor != fileVersionMajor || fileHead.VersionMinor != fileVersionMinor {
+		return nil, errors.New("unsupported file version")
+	}
+
+	if _, err := reader.Seek(int64(fileHead.TilePos), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	tileHeads := make([]tileHeader, fileHead.TileCount)
+	if err := binary.Read(reader, binary.LittleEndian, tileHeads); err != nil {
+		return nil, err
+	}
+
+	tileSet := &TileSet{Tiles: make([]Tile, len(tileHeads))}
+	for i, tileHead := range tileHeads {
+		tile := &tileSet.Tiles[i]
+		*tile = Tile{
+			Size:          math.Vec2i{X: int(tileHead.Width), Y: int(tileHead.Height)},
+			Direction:     int(tileHead.Direction),
+			RoofHeight:    int(tileHead.RoofHeight),
+			MaterialFlags: int(tileHead.MaterialFlags),
+			Orientation:   int(tileHead.Orientation),
+			MainIndex:     int(tileHead.MainIndex),
+			SubIndex:      int(tileHead.SubIndex),
+			Rarity:        int(tileHead.Rarity),
+		}
+
+		for j, flags := range tileHead.SubtileFlags {
+			tile.SubtileFlags[j] = SubtileFlags(flags)
+		}
+
+		if err := readBlocks(reader, tile, tileHead); err != nil {
+			return nil, err
+		}
+	}
+
+	return tileSet, nil
+}
+
+func readBlocks(reader io.ReadSeeker, tile *Tile, header tileHeader) error {
+	if header.BlockCount == 0 {
+		return nil
+	}
+
+	if _, err := reader.Seek(int64(header.BlockPos), io.SeekStart); err != nil {
+		return err
+	}
+
+	blockHeads := make([]blockHeader, header.BlockCount)
+	if err := binary.Read(reader, binary.LittleEndian, blockHeads); err != nil {
+		return err
+	}
+
+	tile.Blocks = make([]Block, len(blockHeads))
+	for i, blockHead := range blockHeads {
+		if _, err := reader.Seek(int64(header.BlockPos+blockHead.DataOffset), io.SeekStart); err != nil {
+			return err
+		}
+
+		data := make([]byte, blockHead.Length)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return err
+		}
+
+		block := Block{
+			Offset: math.Vec2i{X: int(blockHead.X), Y: int(blockHead.Y)},
+			Grid:   math.Vec2i{X: int(blockHead.GridX), Y: int(blockHead.GridY)},
+			Format: BlockFormat(blockHead.Format),
+		}
+
+		var err error
+		if block.Format == BlockIsometric {
+			block.Size = math.Vec2i{X: blockWidth, Y: blockIsometricHeight}
+			block.Data, err = decodeIsometricBlock(data)
+		} else {
+			block.Format = BlockRle
+			block.Size = math.Vec2i{X: blockWidth, Y: blockHeight}
+			block.Data, err = decodeRleBlock(data)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		tile.Blocks[i] = block
+	}
+
+	return nil
+}
+
+func decodeIsometricBlock(data []byte) ([]byte, error) {
+	if len(data) != blockIsometricSize {
+		return nil, errors.New("invalid isometric block size")
+	}
+
+	var (
+		pixels = make([]byte, blockWidth*blockIsometricHeight)
+		offset int
+	)
+
+	for y := 0; y < blockIsometricHeight; y++ {
+		length := isometricLengths[y]
+		copy(pixels[y*blockWidth+isometricOffsets[y]:], data[offset:offset+length])
+		offset += length
+	}
+
+	return pixels, nil
+}
+
+func decodeRleBlock(data []byte) ([]byte, error) {
+	var (
+		pixels = make([]byte, blockWidth*blockHeight)
+		x, y   int
+	)
+
+	for offset := 0; offset+1 < len(data); {
+		skip, length := int(data[offset]), int(data[offset+1])
+		offset += 2
+
+		if skip == 0 && length == 0 {
+			x = 0
+			y++
+			continue
+		}
+
+		x += skip
+		if offset+length > len(data) || y >= blockHeight || x+length > blockWidth {
+			return nil, errors.New("invalid rle block data")
+		}
+
+		copy(pixels[y*blockWidth+x:], data[offset:offset+length])
+		offset += length
+		x += length
+	}
+
+	return pixels, nil
+}
+
+func (t *Tile) Subtile(x, y int) SubtileFlags {
+	return t.SubtileFlags[y*subtileCount+x]
+}
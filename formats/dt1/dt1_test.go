@@ -0,0 +1,118 @@
+package dt1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+func buildTileSet(t *testing.T) []byte {
+	var (
+		isometric = make([]byte, blockIsometricSize)
+		rle       = []byte{2, 3, 7, 8, 9, 0, 0, 30, 2, 4, 5}
+		tilePos   = uint32(binary.Size(fileHeader{}))
+		blockPos  = tilePos + uint32(binary.Size(tileHeader{}))
+		dataPos   = uint32(2 * binary.Size(blockHeader{}))
+	)
+
+	for i := range isometric {
+		isometric[i] = byte(i + 1)
+	}
+
+	tileHead := tileHeader{
+		Direction:   3,
+		RoofHeight:  80,
+		Height:      -96,
+		Width:       160,
+		Orientation: OrientationLeftWall,
+		MainIndex:   2,
+		SubIndex:    5,
+		Rarity:      1,
+		BlockPos:    blockPos,
+		BlockSize:   2*uint32(binary.Size(blockHeader{})) + uint32(len(isometric)+len(rle)),
+		BlockCount:  2,
+	}
+
+	tileHead.SubtileFlags[0] = byte(SubtileBlockWalk)
+	tileHead.SubtileFlags[7] = byte(SubtileBlockWalk | SubtileBlockLightSight)
+
+	var buff bytes.Buffer
+	for _, value := range []interface{}{
+		fileHeader{VersionMajor: fileVersionMajor, VersionMinor: fileVersionMinor, TileCount: 1, TilePos: tilePos},
+		tileHead,
+		blockHeader{X: 64, Y: 16, GridX: 1, GridY: 2, Format: int16(BlockIsometric), Length: uint32(len(isometric)), DataOffset: dataPos},
+		blockHeader{X: 0, Y: -32, Length: uint32(len(rle)), DataOffset: dataPos + uint32(len(isometric))},
+		isometric,
+		rle,
+	} {
+		if err := binary.Write(&buff, binary.LittleEndian, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return buff.Bytes()
+}
+
+func TestNewFromReader(t *testing.T) {
+	tileSet, err := NewFromReader(bytes.NewReader(buildTileSet(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tileSet.Tiles) != 1 {
+		t.Fatalf("tile count: %d", len(tileSet.Tiles))
+	}
+
+	tile := tileSet.Tiles[0]
+	if tile.Size != (math.Vec2i{X: 160, Y: -96}) || tile.Orientation != OrientationLeftWall || tile.MainIndex != 2 || tile.SubIndex != 5 {
+		t.Errorf("tile: %+v", tile)
+	}
+
+	if tile.Subtile(0, 0) != SubtileBlockWalk || tile.Subtile(2, 1) != SubtileBlockWalk|SubtileBlockLightSight || tile.Subtile(4, 4) != 0 {
+		t.Errorf("subtile flags: %v", tile.SubtileFlags)
+	}
+
+	if len(tile.Blocks) != 2 {
+		t.Fatalf("block count: %d", len(tile.Blocks))
+	}
+
+	isometric := tile.Blocks[0]
+	if isometric.Format != BlockIsometric || isometric.Size != (math.Vec2i{X: 32, Y: 15}) || isometric.Offset != (math.Vec2i{X: 64, Y: 16}) || isometric.Grid != (math.Vec2i{X: 1, Y: 2}) {
+		t.Errorf("isometric block: %+v", isometric)
+	}
+
+	if isometric.Data[14] != 1 || isometric.Data[17] != 4 || isometric.Data[18] != 0 || isometric.Data[13] != 0 {
+		t.Errorf("isometric first row: %v", isometric.Data[:32])
+	}
+
+	if row := isometric.Data[7*32 : 8*32]; row[0] != 113 || row[31] != 144 {
+		t.Errorf("isometric middle row: %v", row)
+	}
+
+	if isometric.Data[14*32+14] != 253 || isometric.Data[14*32+17] != 0 {
+		t.Errorf("isometric last row: %v", isometric.Data[14*32:])
+	}
+
+	rle := tile.Blocks[1]
+	if rle.Format != BlockRle || rle.Size != (math.Vec2i{X: 32, Y: 32}) || rle.Offset != (math.Vec2i{X: 0, Y: -32}) {
+		t.Errorf("rle block: %+v", rle)
+	}
+
+	expected := make([]byte, 32*32)
+	copy(expected[2:], []byte{7, 8, 9})
+	copy(expected[32+30:], []byte{4, 5})
+	if !bytes.Equal(rle.Data, expected) {
+		t.Errorf("rle block data: %v", rle.Data)
+	}
+}
+
+func TestInvalidVersion(t *testing.T) {
+	data := buildTileSet(t)
+	data[0] = 8
+
+	if _, err := NewFromReader(bytes.NewReader(data)); err == nil {
+		t.Error("invalid version was accepted")
+	}
+}
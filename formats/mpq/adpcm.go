@@ -0,0 +1,121 @@
+package mpq
+
+import "encoding/binary"
+
+var (
+	adpcmStepSizes = [89]int{
+		7, 8, 9, 10, 11, 12, 13, 14, 16, 17, 19, 21, 23, 25, 28, 31, 34, 37, 41, 45, 50, 55, 60, 66, 73, 80, 88, 97,
+		107, 118, 130, 143, 157, 173, 190, 209, 230, 253, 279, 307, 337, 371, 408, 449, 494, 544, 598, 658, 724, 796,
+		876, 963, 1060, 1166, 1282, 1411, 1552, 1707, 1878, 2066, 2272, 2499, 2749, 3024, 3327, 3660, 4026, 4428, 4871,
+		5358, 5894, 6484, 7132, 7845, 8630, 9493, 10442, 11487, 12635, 13899, 15289, 16818, 18500, 20350, 22385, 24623,
+		27086, 29794, 32767,
+	}
+	adpcmStepIndexDeltas = [32]int{
+		-1, 0, -1, 4, -1, 2, -1, 6, -1, 0, -1, 4, -1, 2, -1, 6,
+		-1, 0, -1, 4, -1, 2, -1, 6, -1, 0, -1, 4, -1, 2, -1, 6,
+	}
+)
+
+const adpcmInitialStepIndex = 0x2c
+
+func decompressAdpcm(data []byte, size, channelCount int) []byte {
+	output := make([]byte, 0, size)
+	if len(data) < 2 {
+		return output
+	}
+
+	writeSample := func(sample int) bool {
+		if len(output)+2 > size {
+			return false
+		}
+
+		output = append(output, 0, 0)
+		binary.LittleEndian.PutUint16(output[len(output)-2:], uint16(int16(sample)))
+		return true
+	}
+
+	var (
+		bitShift    = uint(data[1])
+		samples     [2]int
+		stepIndices = [2]int{adpcmInitialStepIndex, adpcmInitialStepIndex}
+		offset      = 2
+	)
+
+	for i := 0; i < channelCount; i++ {
+		if offset+2 > len(data) {
+			return output
+		}
+
+		samples[i] = int(int16(binary.LittleEndian.Uint16(data[offset:])))
+		offset += 2
+
+		if !writeSample(samples[i]) {
+			return output
+		}
+	}
+
+	channel := channelCount - 1
+	for ; offset < len(data); offset++ {
+		value := int(data[offset])
+		channel = (channel + 1) % channelCount
+
+		if value&0x80 != 0 {
+			switch value & 0x7f {
+			case 0:
+				if stepIndices[channel] != 0 {
+					stepIndices[channel]--
+				}
+				if !writeSample(samples[channel]) {
+					return output
+				}
+			case 1:
+				if stepIndices[channel] += 8; stepIndices[channel] > len(adpcmStepSizes)-1 {
+					stepIndices[channel] = len(adpcmStepSizes) - 1
+				}
+				channel = (channel + 1) % channelCount
+			case 2:
+				channel = (channel + 1) % channelCount
+			default:
+				if stepIndices[channel] -= 8; stepIndices[channel] < 0 {
+					stepIndices[channel] = 0
+				}
+				channel = (channel + 1) % channelCount
+			}
+
+			continue
+		}
+
+		var (
+			stepSize   = adpcmStepSizes[stepIndices[channel]]
+			difference = stepSize >> bitShift
+		)
+
+		for bit := uint(0); bit < 6; bit++ {
+			if value&(1<<bit) != 0 {
+				difference += stepSize >> bit
+			}
+		}
+
+		if value&0x40 != 0 {
+			if samples[channel] -= difference; samples[channel] < -32768 {
+				samples[channel] = -32768
+			}
+		} else {
+			if samples[channel] += difference; samples[channel] > 32767 {
+				samples[channel] = 32767
+			}
+		}
+
+		if !writeSample(samples[channel]) {
+			return output
+		}
+
+		if stepIndices[channel] += adpcmStepIndexDeltas[value&0x1f]; stepIndices[channel] < 0 {
+			stepIndices[channel] = 0
+		} else if stepIndices[channel] > len(adpcmStepSizes)-1 {
+			stepIndices[channel] = len(adpcmStepSizes) - 1
+		}
+	}
+
+	return output
+}
@@ -0,0 +1,866 @@
+//go:build !stormlib
+// +build !stormlib
+
+package mpq
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+type Archive struct {
+	file       *os.File
+	offset     int64
+	header     archiveHeader
+	hashTable  []hashEntry
+	blockTable []blockEntry
+	paths      map[string]string
+	unnamed    map[string]hashEntry
+	dirs       map[string][]string
+	pathMutex  sync.RWMutex
+}
+
+func NewFromFile(path string) (*Archive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Archive{file: file}
+	if err := a.readHeader(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if err := a.readTables(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if err := a.buildPathMap(); err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *Archive) Close() error {
+	a.pathMutex.Lock()
+	defer a.pathMutex.Unlock()
+
+	if a.file == nil {
+		return errors.New("archive is not open")
+	}
+
+	err := a.file.Close()
+
+	a.file = nil
+	a.hashTable = nil
+	a.blockTable = nil
+	a.paths = nil
+	a.unnamed = nil
+	a.dirs = nil
+
+	return err
+}
+
+func (a *Archive) OpenFile(path string) (*File, error) {
+	a.pathMutex.RLock()
+	defer a.pathMutex.RUnlock()
+
+	return a.openFile(path)
+}
+
+func (a *Archive) openFile(path string) (*File, error) {
+	block, path, err := a.findBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return a.openBlock(block, path)
+}
+
+func (a *Archive) fileSize(path string) (int64, error) {
+	block, _, err := a.findBlock(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if block.Flags&fileExists == 0 || block.Flags&fileDeleteMarker != 0 {
+		return 0, errors.New("file does not exist")
+	}
+
+	return int64(block.FileSize), nil
+}
+
+func (a *Archive) findBlock(path string) (blockEntry, string, error) {
+	if pathInt, ok := a.paths[path]; ok {
+		path = pathInt
+	}
+
+	if hash, ok := a.unnamed[path]; ok {
+		return a.blockTable[hash.BlockIndex], "", nil
+	}
+
+	hash, err := a.findHashEntry(path)
+	if err != nil {
+		return blockEntry{}, "", err
+	}
+
+	if int(hash.BlockIndex) >= len(a.blockTable) {
+		return blockEntry{}, "", errors.New("invalid block index")
+	}
+
+	return a.blockTable[hash.BlockIndex], path, nil
+}
+
+func (a *Archive) openBlock(block blockEntry, path string) (*File, error) {
+	if block.Flags&fileExists == 0 || block.Flags&fileDeleteMarker != 0 {
+		return nil, errors.New("file does not exist")
+	}
+
+	file := &File{
+		archive:     a,
+		block:       block,
+		sectorIndex: -1,
+	}
+
+	if block.Flags&fileEncrypted != 0 {
+		if len(path) > 0 {
+			file.key = fileKey(path, block)
+		} else {
+			key, err := a.detectBlockKey(block)
+			if err != nil {
+				return nil, err
+			}
+
+			file.key = key
+		}
+	}
+
+	if err := file.readSectorOffsets(); err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+func (a *Archive) hasFile(path string) bool {
+	hash, err := a.findHashEntry(path)
+	if err != nil || int(hash.BlockIndex) >= len(a.blockTable) {
+		return false
+	}
+
+	block := a.blockTable[hash.BlockIndex]
+	return block.Flags&fileExists != 0 && block.Flags&fileDeleteMarker == 0
+}
+
+func (a *Archive) buildUnnamedMap() {
+	for pathInt := range a.unnamed {
+		delete(a.paths, sanitizePath(pathInt))
+	}
+
+	a.unnamed = make(map[string]hashEntry)
+
+	names := make(map[[2]uint32]bool)
+	for _, pathInt := range a.paths {
+		names[[2]uint32{hashString(pathInt, hashTypeNameA), hashString(pathInt, hashTypeNameB)}] = true
+	}
+
+	for _, pathInt := range []string{"(listfile)", "(attributes)", "(signature)"} {
+		names[[2]uint32{hashString(pathInt, hashTypeNameA), hashString(pathInt, hashTypeNameB)}] = true
+	}
+
+	namedBlocks := make(map[uint32]bool)
+	for _, hash := range a.hashTable {
+		if names[[2]uint32{hash.NameA, hash.NameB}] {
+			namedBlocks[hash.BlockIndex] = true
+		}
+	}
+
+	for _, hash := range a.hashTable {
+		if hash.BlockIndex == hashEntryEmpty || hash.BlockIndex == hashEntryDeleted || int(hash.BlockIndex) >= len(a.blockTable) {
+			continue
+		}
+
+		if namedBlocks[hash.BlockIndex] {
+			continue
+		}
+
+		block := a.blockTable[hash.BlockIndex]
+		if block.Flags&fileExists == 0 || block.Flags&fileDeleteMarker != 0 {
+			continue
+		}
+
+		pathInt := fmt.Sprintf("File%08X.xxx", hash.BlockIndex)
+		a.unnamed[pathInt] = hash
+		a.paths[sanitizePath(pathInt)] = pathInt
+	}
+}
+
+func (a *Archive) detectBlockKey(block blockEntry) (uint32, error) {
+	if block.Flags&fileSingleUnit != 0 || block.Flags&(fileCompress|fileImplode) == 0 {
+		return 0, errors.New("cannot determine key of unnamed encrypted file")
+	}
+
+	data := make([]byte, 8)
+	if _, err := a.file.ReadAt(data, a.offset+int64(block.FilePos)); err != nil {
+		return 0, err
+	}
+
+	var (
+		sectorCount = (block.FileSize + a.sectorSize() - 1) / a.sectorSize()
+		tableSize   = (sectorCount + 1) * 4
+	)
+
+	if block.Flags&fileSectorCrc != 0 {
+		tableSize += 4
+	}
+
+	encrypted := []uint32{binary.LittleEndian.Uint32(data), binary.LittleEndian.Uint32(data[4:])}
+	key, ok := detectKey(encrypted, tableSize, a.sectorSize())
+	if !ok {
+		return 0, errors.New("cannot determine key of unnamed encrypted file")
+	}
+
+	return key + 1, nil
+}
+
+func (a *Archive) readHeader() error {
+	fileInfo, err := a.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	for offset := int64(0); offset+32 <= fileInfo.Size(); offset += 0x200 {
+		var signature [4]byte
+		if _, err := a.file.ReadAt(signature[:], offset); err != nil {
+			return err
+		}
+
+		switch signature {
+		case userDataSignature:
+			var userData userDataHeader
+			if err := a.readStruct(offset, &userData); err != nil {
+				return err
+			}
+
+			headerOffset := offset + int64(userData.HeaderOffset)
+			if err := a.readStruct(headerOffset, &a.header); err != nil {
+				return err
+			}
+
+			if a.header.Signature != archiveSignature {
+				return errors.New("invalid archive header")
+			}
+
+			a.offset = headerOffset
+			return nil
+		case archiveSignature:
+			if err := a.readStruct(offset, &a.header); err != nil {
+				return err
+			}
+
+			a.offset = offset
+			return nil
+		}
+	}
+
+	return errors.New("archive header not found")
+}
+
+func (a *Archive) readTables() error {
+	a.hashTable = make([]hashEntry, a.header.HashTableSize)
+	if err := a.readTable(a.header.HashTablePos, "(hash table)", a.hashTable); err != nil {
+		return err
+	}
+
+	a.blockTable = make([]blockEntry, a.header.BlockTableSize)
+	if err := a.readTable(a.header.BlockTablePos, "(block table)", a.blockTable); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (a *Archive) readTable(position uint32, name string, table interface{}) error {
+	size := binary.Size(table)
+	if size < 0 {
+		return errors.New("invalid table type")
+	}
+
+	data := make([]byte, size)
+	if _, err := a.file.ReadAt(data, a.offset+int64(position)); err != nil {
+		return err
+	}
+
+	decryptBytes(data, hashString(name, hashTypeFileKey))
+	return binary.Read(bytes.NewReader(data), binary.LittleEndian, table)
+}
+
+func (a *Archive) readStruct(offset int64, data interface{}) error {
+	return binary.Read(io.NewSectionReader(a.file, offset, int64(binary.Size(data))), binary.LittleEndian, data)
+}
+
+func (a *Archive) findHashEntry(path string) (*hashEntry, error) {
+	if len(a.hashTable) == 0 {
+		return nil, errors.New("archive hash table is empty")
+	}
+
+	var (
+		mask  = uint32(len(a.hashTable) - 1)
+		start = hashString(path, hashTypeOffset) & mask
+		nameA = hashString(path, hashTypeNameA)
+		nameB = hashString(path, hashTypeNameB)
+		match *hashEntry
+	)
+
+	for i := start; ; {
+		hash := &a.hashTable[i]
+		if hash.BlockIndex == hashEntryEmpty {
+			break
+		}
+
+		if hash.BlockIndex != hashEntryDeleted && hash.NameA == nameA && hash.NameB == nameB {
+			if hash.Locale == 0 {
+				return hash, nil
+			}
+			if match == nil {
+				match = hash
+			}
+		}
+
+		if i = (i + 1) & mask; i == start {
+			break
+		}
+	}
+
+	if match == nil {
+		return nil, errors.New("file not found in archive")
+	}
+
+	return match, nil
+}
+
+func (a *Archive) sectorSize() uint32 {
+	return 0x200 << a.header.SectorSizeShift
+}
+
+func (a *Archive) Verify() (*VerifyReport, error) {
+	if a.file == nil {
+		return nil, errors.New("archive is not open")
+	}
+
+	a.pathMutex.RLock()
+	defer a.pathMutex.RUnlock()
+
+	type hashKey struct {
+		nameA, nameB     uint32
+		locale, platform uint16
+	}
+
+	var (
+		report     = &VerifyReport{Blocks: len(a.blockTable), Issues: []VerifyIssue{}}
+		names      = make(map[[2]uint32]string)
+		blockNames = make(map[int]string)
+		reachable  = make(map[int]bool)
+		hashKeys   = make(map[hashKey]int)
+	)
+
+	for _, pathInt := range a.paths {
+		names[[2]uint32{hashString(pathInt, hashTypeNameA), hashString(pathInt, hashTypeNameB)}] = pathInt
+	}
+
+	for _, pathInt := range []string{"(listfile)", "(attributes)", "(signature)"} {
+		names[[2]uint32{hashString(pathInt, hashTypeNameA), hashString(pathInt, hashTypeNameB)}] = pathInt
+	}
+
+	for i, hash := range a.hashTable {
+		if hash.BlockIndex == hashEntryEmpty || hash.BlockIndex == hashEntryDeleted {
+			continue
+		}
+
+		report.HashEntries++
+
+		var (
+			index = int(hash.BlockIndex)
+			name  = names[[2]uint32{hash.NameA, hash.NameB}]
+		)
+
+		if index >= len(a.blockTable) {
+			report.addIssue(VerifyInvalidBlock, -1, name, "hash entry %d references block %d of %d", i, index, len(a.blockTable))
+			continue
+		}
+
+		key := hashKey{hash.NameA, hash.NameB, hash.Locale, hash.Platform}
+		if other, ok := hashKeys[key]; ok {
+			report.addIssue(VerifyCollision, index, name, "hash entry %d duplicates hash entry %d", i, other)
+		} else {
+			hashKeys[key] = i
+		}
+
+		reachable[index] = true
+		if len(name) > 0 {
+			blockNames[index] = name
+		}
+	}
+
+	for pathInt, hash := range a.unnamed {
+		names[[2]uint32{hash.NameA, hash.NameB}] = pathInt
+	}
+
+	if f, err := a.openFile("(listfile)"); err == nil {
+		pathInts, err := parseListFile(f)
+		f.Close()
+		if err != nil {
+			report.addIssue(VerifyCorrupt, -1, "(listfile)", "%v", err)
+		}
+
+		for _, pathInt := range pathInts {
+			if !a.hasFile(pathInt) {
+				report.addIssue(VerifyUnresolved, -1, pathInt, "listfile entry does not resolve to a file")
+			}
+		}
+	}
+
+	var (
+		crcs []uint32
+		md5s [][md5.Size]byte
+	)
+
+	if _, err := a.findHashEntry("(attributes)"); err == nil {
+		if crcs, md5s, err = a.readAttributes(); err != nil {
+			report.addIssue(VerifyCorrupt, -1, "(attributes)", "%v", err)
+		} else {
+			report.Attributes = true
+		}
+	}
+
+	for index, block := range a.blockTable {
+		if block.Flags&fileExists == 0 {
+			continue
+		}
+
+		if !reachable[index] {
+			report.addIssue(VerifyUnreachable, index, "", "block is not referenced by the hash table")
+			continue
+		}
+
+		if block.Flags&fileDeleteMarker != 0 {
+			continue
+		}
+
+		name := blockNames[index]
+		if _, ok := a.unnamed[name]; ok && block.Flags&fileEncrypted != 0 {
+			if _, err := a.detectBlockKey(block); err != nil {
+				report.addIssue(VerifyUnnamed, index, name, "encrypted block cannot be verified without a file name")
+				continue
+			}
+		}
+
+		data, err := a.verifyBlock(index, name, report)
+		if err != nil {
+			report.addIssue(VerifyCorrupt, index, name, "%v", err)
+			continue
+		}
+
+		report.VerifiedFiles++
+
+		if index < len(crcs) && crcs[index] != 0 && crc32.ChecksumIEEE(data) != crcs[index] {
+			report.addIssue(VerifyCrc32, index, name, "crc32 does not match attributes")
+		}
+
+		if index < len(md5s) && md5s[index] != [md5.Size]byte{} && md5.Sum(data) != md5s[index] {
+			report.addIssue(VerifyMd5, index, name, "md5 does not match attributes")
+		}
+	}
+
+	return report, nil
+}
+
+func (a *Archive) verifyBlock(index int, name string, report *VerifyReport) ([]byte, error) {
+	if _, ok := a.unnamed[name]; ok {
+		name = ""
+	}
+
+	file, err := a.openBlock(a.blockTable[index], name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if file.block.Flags&fileSectorCrc != 0 && file.block.Flags&fileSingleUnit == 0 && file.block.Flags&(fileCompress|fileImplode) != 0 {
+		if err := file.verifySectors(index, name, report); err != nil {
+			return nil, err
+		}
+	}
+
+	return ioutil.ReadAll(file)
+}
+
+func (a *Archive) readAttributes() ([]uint32, [][md5.Size]byte, error) {
+	file, err := a.openFile("(attributes)")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		reader = bytes.NewReader(data)
+		header struct {
+			Version uint32
+			Flags   uint32
+		}
+	)
+
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return nil, nil, err
+	}
+
+	if header.Version != attributesVersion {
+		return nil, nil, errors.New("unsupported attributes version")
+	}
+
+	var (
+		crcs []uint32
+		md5s [][md5.Size]byte
+	)
+
+	if header.Flags&attributesCrc32 != 0 {
+		crcs = make([]uint32, len(a.blockTable))
+		if err := binary.Read(reader, binary.LittleEndian, crcs); err != nil {
+			return nil, nil, errors.New("attributes crc32 table is truncated")
+		}
+	}
+
+	if header.Flags&attributesFileTime != 0 {
+		if _, err := reader.Seek(int64(len(a.blockTable)*8), io.SeekCurrent); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if header.Flags&attributesMd5 != 0 {
+		md5s = make([][md5.Size]byte, len(a.blockTable))
+		if err := binary.Read(reader, binary.LittleEndian, md5s); err != nil {
+			return nil, nil, errors.New("attributes md5 table is truncated")
+		}
+	}
+
+	return crcs, md5s, nil
+}
+
+type File struct {
+	archive       *Archive
+	block         blockEntry
+	key           uint32
+	sectorOffsets []uint32
+	sectorIndex   int
+	sectorData    []byte
+	offset        int64
+}
+
+func (f *File) Read(data []byte) (int, error) {
+	if f.archive == nil {
+		return 0, errors.New("file is not open")
+	}
+
+	var count int
+	for count < len(data) {
+		if f.offset >= int64(f.block.FileSize) {
+			return count, io.EOF
+		}
+
+		var (
+			sectorSize   = int64(f.sectorLength())
+			sectorIndex  = int(f.offset / sectorSize)
+			sectorOffset = int(f.offset % sectorSize)
+		)
+
+		if err := f.loadSector(sectorIndex); err != nil {
+			return count, err
+		}
+
+		copied := copy(data[count:], f.sectorData[sectorOffset:])
+		count += copied
+		f.offset += int64(copied)
+	}
+
+	return count, nil
+}
+
+func (f *File) ReadAt(data []byte, offset int64) (int, error) {
+	if f.archive == nil {
+		return 0, errors.New("file is not open")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("cannot read before beginning of file")
+	}
+
+	var count int
+	for count < len(data) {
+		position := offset + int64(count)
+		if position >= int64(f.block.FileSize) {
+			return count, io.EOF
+		}
+
+		var (
+			sectorSize   = int64(f.sectorLength())
+			sectorIndex  = int(position / sectorSize)
+			sectorOffset = int(position % sectorSize)
+		)
+
+		sectorData, err := f.readSector(sectorIndex, f.sectorDataLength(sectorIndex))
+		if err != nil {
+			return count, err
+		}
+
+		count += copy(data[count:], sectorData[sectorOffset:])
+	}
+
+	return count, nil
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	result := f.offset
+	switch whence {
+	case io.SeekStart:
+		result = offset
+	case io.SeekCurrent:
+		result = f.offset + offset
+	case io.SeekEnd:
+		result = int64(f.block.FileSize) + offset
+	}
+
+	if result < 0 {
+		return f.offset, errors.New("cannot seek before beginning of file")
+	}
+
+	f.offset = result
+	return f.offset, nil
+}
+
+func (f *File) Size() int64 {
+	return int64(f.block.FileSize)
+}
+
+func (f *File) Close() error {
+	if f.archive == nil {
+		return errors.New("file is not open")
+	}
+
+	f.archive = nil
+	f.sectorOffsets = nil
+	f.sectorData = nil
+
+	return nil
+}
+
+func (f *File) sectorLength() uint32 {
+	if f.block.Flags&fileSingleUnit != 0 {
+		return f.block.FileSize
+	}
+
+	return f.archive.sectorSize()
+}
+
+func (f *File) sectorDataLength(index int) uint32 {
+	size := f.sectorLength()
+	if remainder := f.block.FileSize - uint32(index)*size; remainder < size {
+		size = remainder
+	}
+
+	return size
+}
+
+func (f *File) sectorCount() int {
+	sectorSize := f.sectorLength()
+	if sectorSize == 0 {
+		return 0
+	}
+
+	return int((f.block.FileSize + sectorSize - 1) / sectorSize)
+}
+
+func (f *File) readSectorOffsets() error {
+	if f.block.Flags&fileSingleUnit != 0 {
+		f.sectorOffsets = []uint32{0, f.block.CompressedSize}
+		return nil
+	}
+
+	sectorCount := f.sectorCount()
+	if f.block.Flags&(fileCompress|fileImplode) == 0 {
+		f.sectorOffsets = make([]uint32, sectorCount+1)
+		for i := range f.sectorOffsets {
+			f.sectorOffsets[i] = uint32(i) * f.sectorLength()
+		}
+
+		f.sectorOffsets[sectorCount] = f.block.CompressedSize
+		return nil
+	}
+
+	offsetCount := sectorCount + 1
+	if f.block.Flags&fileSectorCrc != 0 {
+		offsetCount++
+	}
+
+	data := make([]byte, offsetCount*4)
+	if _, err := f.archive.file.ReadAt(data, f.archive.offset+int64(f.block.FilePos)); err != nil {
+		return err
+	}
+
+	if f.block.Flags&fileEncrypted != 0 {
+		decryptBytes(data, f.key-1)
+	}
+
+	f.sectorOffsets = make([]uint32, offsetCount)
+	for i := range f.sectorOffsets {
+		f.sectorOffsets[i] = binary.LittleEndian.Uint32(data[i*4:])
+		if i > 0 && f.sectorOffsets[i] < f.sectorOffsets[i-1] {
+			return errors.New("invalid sector offset table")
+		}
+	}
+
+	if f.sectorOffsets[offsetCount-1] > f.block.CompressedSize {
+		return errors.New("invalid sector offset table")
+	}
+
+	return nil
+}
+
+func (f *File) loadSector(index int) error {
+	if index == f.sectorIndex {
+		return nil
+	}
+
+	if index >= f.sectorCount() {
+		return errors.New("sector index out of range")
+	}
+
+	data, err := f.readSector(index, f.sectorDataLength(index))
+	if err != nil {
+		return err
+	}
+
+	f.sectorIndex = index
+	f.sectorData = data
+
+	return nil
+}
+
+func (f *File) verifySectors(index int, name string, report *VerifyReport) error {
+	crcs, err := f.readSectorCrcs()
+	if err != nil {
+		report.addIssue(VerifySectorCrc, index, name, "%v", err)
+		return nil
+	}
+
+	for i, crc := range crcs {
+		if crc == 0 {
+			continue
+		}
+
+		data, err := f.readRawSector(i)
+		if err != nil {
+			return err
+		}
+
+		f.decryptSector(data, i)
+		if sectorChecksum(data) != crc {
+			report.addIssue(VerifySectorCrc, index, name, "sector %d checksum mismatch", i)
+		}
+	}
+
+	return nil
+}
+
+func (f *File) readSectorCrcs() ([]uint32, error) {
+	count := f.sectorCount()
+	if len(f.sectorOffsets) < count+2 {
+		return nil, errors.New("sector checksum table is missing")
+	}
+
+	data, err := f.readRawSector(count)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < count*4 {
+		if data, err = decompress(data, count*4); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(data) < count*4 {
+		return nil, errors.New("sector checksum table is truncated")
+	}
+
+	crcs := make([]uint32, count)
+	for i := range crcs {
+		crcs[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+
+	return crcs, nil
+}
+
+func (f *File) readRawSector(index int) ([]byte, error) {
+	var (
+		sectorStart = f.sectorOffsets[index]
+		sectorEnd   = f.sectorOffsets[index+1]
+		data        = make([]byte, sectorEnd-sectorStart)
+	)
+
+	if _, err := f.archive.file.ReadAt(data, f.archive.offset+int64(f.block.FilePos)+int64(sectorStart)); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (f *File) decryptSector(data []byte, index int) {
+	if f.block.Flags&fileEncrypted != 0 {
+		decryptBytes(data, f.key+uint32(index))
+	}
+}
+
+func (f *File) readSector(index int, size uint32) ([]byte, error) {
+	data, err := f.readRawSector(index)
+	if err != nil {
+		return nil, err
+	}
+
+	f.decryptSector(data, index)
+
+	if uint32(len(data)) < size {
+		switch {
+		case f.block.Flags&fileCompress != 0:
+			data, err = decompress(data, int(size))
+		case f.block.Flags&fileImplode != 0:
+			data, err = explode(data, int(size))
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if uint32(len(data)) < size {
+		return nil, errors.New("sector data is truncated")
+	}
+
+	return data[:size], nil
+}
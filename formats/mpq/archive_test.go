@@ -0,0 +1,456 @@
+//go:build !stormlib
+// +build !stormlib
+
+package mpq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/adler32"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestVerify(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "test.mpq")
+		data = bytes.Repeat([]byte("lazarus"), 100)
+	)
+
+	w, err := NewWriter(path, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddFile("data\\plain.txt", data, FileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddFile("data\\zlib.txt", data, FileOptions{Compression: CompressZlib, Encrypt: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	verify := func() *VerifyReport {
+		a, err := NewFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer a.Close()
+
+		report, err := a.Verify()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return report
+	}
+
+	if report := verify(); !report.Ok() || report.VerifiedFiles != 4 || !report.Attributes {
+		t.Fatalf("unexpected report for valid archive: %+v", report)
+	}
+
+	archiveData, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index := bytes.Index(archiveData, data)
+	if index < 0 {
+		t.Fatal("stored file data not found")
+	}
+
+	archiveData[index] ^= 0xff
+	if err := ioutil.WriteFile(path, archiveData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	kinds := make(map[VerifyIssueKind]bool)
+	for _, issue := range verify().Issues {
+		if issue.Path != "data\\plain.txt" {
+			t.Errorf("unexpected issue: %s", issue)
+		}
+
+		kinds[issue.Kind] = true
+	}
+
+	if !kinds[VerifyCrc32] || !kinds[VerifyMd5] {
+		t.Errorf("checksum mismatches not reported: %v", kinds)
+	}
+}
+
+func writeSectorCrcArchive(t *testing.T, path, name string, data []byte, corruptSector int) {
+	const sectorSize = 0x200
+
+	var (
+		count   = (len(data) + sectorSize - 1) / sectorSize
+		offsets = make([]uint32, count+2)
+		sectors [][]byte
+		crcs    = make([]byte, count*4)
+		block   = blockEntry{FilePos: 0x20, FileSize: uint32(len(data)), Flags: fileExists | fileCompress | fileEncrypted | fileSectorCrc}
+		key     = fileKey(name, block)
+	)
+
+	offsets[0] = uint32(len(offsets) * 4)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * sectorSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		sector, err := compress(data[i*sectorSize:end], compressionZlib)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// storm seeds adler32 with zero, so derive the expected value from the standard checksum
+		var (
+			checksum = adler32.Checksum(sector)
+			a        = (checksum&0xffff + 65520) % 65521
+			b        = (checksum>>16 + 65521 - uint32(len(sector))%65521) % 65521
+		)
+
+		binary.LittleEndian.PutUint32(crcs[i*4:], b<<16|a)
+		if i == corruptSector {
+			crcs[i*4] ^= 0xff
+		}
+
+		encryptBytes(sector, key+uint32(i))
+		sectors = append(sectors, sector)
+		offsets[i+1] = offsets[i] + uint32(len(sector))
+	}
+
+	offsets[count+1] = offsets[count] + uint32(len(crcs))
+	block.CompressedSize = offsets[count+1]
+
+	var fileData bytes.Buffer
+	binary.Write(&fileData, binary.LittleEndian, offsets)
+	encryptBytes(fileData.Bytes(), key-1)
+	for _, sector := range sectors {
+		fileData.Write(sector)
+	}
+	fileData.Write(crcs)
+
+	hashTable := make([]hashEntry, 4)
+	for i := range hashTable {
+		hashTable[i] = hashEntry{hashEntryEmpty, hashEntryEmpty, 0xffff, 0xffff, hashEntryEmpty}
+	}
+	hashTable[hashString(name, hashTypeOffset)&3] = hashEntry{NameA: hashString(name, hashTypeNameA), NameB: hashString(name, hashTypeNameB)}
+
+	var hashData, blockData bytes.Buffer
+	binary.Write(&hashData, binary.LittleEndian, hashTable)
+	encryptBytes(hashData.Bytes(), hashString("(hash table)", hashTypeFileKey))
+	binary.Write(&blockData, binary.LittleEndian, []blockEntry{block})
+	encryptBytes(blockData.Bytes(), hashString("(block table)", hashTypeFileKey))
+
+	header := archiveHeader{
+		Signature:      archiveSignature,
+		HeaderSize:     0x20,
+		HashTablePos:   0x20 + uint32(fileData.Len()),
+		BlockTablePos:  0x20 + uint32(fileData.Len()+hashData.Len()),
+		HashTableSize:  4,
+		BlockTableSize: 1,
+	}
+	header.ArchiveSize = header.BlockTablePos + uint32(blockData.Len())
+
+	var archiveData bytes.Buffer
+	binary.Write(&archiveData, binary.LittleEndian, header)
+	archiveData.Write(fileData.Bytes())
+	archiveData.Write(hashData.Bytes())
+	archiveData.Write(blockData.Bytes())
+
+	if err := ioutil.WriteFile(path, archiveData.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSectorCrcs(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "test.mpq")
+		name = "data\\global\\sectors.bin"
+		data = testCompressionData()[:0x500]
+	)
+
+	verify := func(corruptSector int) *VerifyReport {
+		writeSectorCrcArchive(t, path, name, data, corruptSector)
+
+		a, err := NewFromFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer a.Close()
+
+		if err := a.AddListFile(strings.NewReader(name)); err != nil {
+			t.Fatal(err)
+		}
+
+		file, err := a.OpenFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(result, data) {
+			t.Error("sector checksum file contents do not match")
+		}
+
+		report, err := a.Verify()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return report
+	}
+
+	if report := verify(-1); !report.Ok() || report.VerifiedFiles != 1 {
+		t.Fatalf("unexpected report for valid archive: %+v", report)
+	}
+
+	report := verify(1)
+	if len(report.Issues) != 1 || report.Issues[0].Kind != VerifySectorCrc || report.Issues[0].Message != "sector 1 checksum mismatch" {
+		t.Errorf("unexpected report for corrupt sector checksum: %+v", report)
+	}
+}
+
+func TestUnnamedFiles(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "test.mpq")
+		data  = testCompressionData()
+		names = []string{"data\\global\\encrypted.bin", "data\\global\\plain.bin"}
+	)
+
+	w, err := NewWriter(path, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.writeFile(names[0], data, FileOptions{Compression: CompressZlib, Encrypt: true, FixKey: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.writeFile(names[1], data, FileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	entries := a.Entries()
+	if len(entries) != len(names) {
+		t.Fatalf("entries: %d, expected: %d", len(entries), len(names))
+	}
+
+	for i, entry := range entries {
+		if entry.Named || entry.Path != fmt.Sprintf("file%08x.xxx", i) {
+			t.Errorf("unexpected entry: %+v", entry)
+		}
+
+		if entry.NameA != hashString(names[i], hashTypeNameA) || entry.NameB != hashString(names[i], hashTypeNameB) {
+			t.Errorf("unexpected hashes: %+v", entry)
+		}
+
+		f, err := a.OpenFile(entry.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(result, data) {
+			t.Errorf("%s: contents do not match", entry.Path)
+		}
+	}
+
+	if err := a.AddListFile(strings.NewReader(names[0] + "\r\nunknown.txt\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries = a.Entries()
+	if len(entries) != len(names) || !entries[0].Named || entries[0].Path != sanitizePath(names[0]) || entries[1].Named {
+		t.Errorf("unexpected entries after adding listfile: %+v", entries)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		srcPath = filepath.Join(dir, "source.mpq")
+		data    = testCompressionData()
+	)
+
+	w, err := NewWriter(srcPath, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddFile("data\\removed.bin", data[:300], FileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddFile("data\\named.bin", data, FileOptions{Compression: CompressZlib, Encrypt: true, FixKey: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.writeFile("data\\unnamed.bin", data[:5000], FileOptions{Compression: CompressImplode, Encrypt: true, FixKey: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, hash := range w.hashTable {
+		if hash.NameA == hashString("data\\named.bin", hashTypeNameA) {
+			w.hashTable[i].Locale = 0x409
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := NewFromFile(srcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	copyArchive := func(hashTableSize int) (*Archive, error) {
+		path := filepath.Join(dir, fmt.Sprintf("copy%d.mpq", hashTableSize))
+		w, err := NewWriter(path, hashTableSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"file00000002.xxx", sanitizePath("data\\named.bin")} {
+			if err := w.CopyFile(src, name); err != nil {
+				w.Close()
+				return nil, err
+			}
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		return NewFromFile(path)
+	}
+
+	dst, err := copyArchive(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	if report, err := dst.Verify(); err != nil || !report.Ok() || report.VerifiedFiles != 4 {
+		t.Errorf("unexpected report for copied archive: %+v (%v)", report, err)
+	}
+
+	for i, hash := range src.hashTable {
+		if hash.BlockIndex == hashEntryEmpty {
+			continue
+		}
+
+		copied := dst.hashTable[i]
+		if hash.NameA == hashString("data\\removed.bin", hashTypeNameA) || hash.NameA == hashString("(listfile)", hashTypeNameA) || hash.NameA == hashString("(attributes)", hashTypeNameA) {
+			if copied.BlockIndex != hashEntryDeleted && copied.NameA == hash.NameA {
+				t.Errorf("hash entry %d was copied: %+v", i, copied)
+			}
+			continue
+		}
+
+		if copied.NameA != hash.NameA || copied.NameB != hash.NameB || copied.Locale != hash.Locale {
+			t.Errorf("hash entry %d: %+v, expected: %+v", i, copied, hash)
+		}
+
+		if flags := dst.blockTable[copied.BlockIndex].Flags; flags != src.blockTable[hash.BlockIndex].Flags {
+			t.Errorf("hash entry %d flags: %x, expected: %x", i, flags, src.blockTable[hash.BlockIndex].Flags)
+		}
+	}
+
+	for name, expected := range map[string][]byte{"file00000000.xxx": data[:5000], sanitizePath("data\\named.bin"): data} {
+		f, err := dst.OpenFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil || !bytes.Equal(result, expected) {
+			t.Errorf("%s: contents do not match (%v)", name, err)
+		}
+	}
+
+	if _, err := copyArchive(32); err == nil {
+		t.Error("unnamed file was moved to a different hash table size")
+	}
+}
+
+func TestStaleListFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mpq")
+
+	w, err := NewWriter(path, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddFile("data\\present.bin", []byte("present"), FileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	w.paths = append(w.paths, "data\\missing.bin", "stale\\missing.bin")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	if paths := a.GetPaths(); len(paths) != 1 || paths[0] != sanitizePath("data\\present.bin") {
+		t.Errorf("paths: %v", paths)
+	}
+
+	if err := fstest.TestFS(a, "data/present.bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := a.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unresolved := make(map[string]bool)
+	for _, issue := range report.Issues {
+		if issue.Kind != VerifyUnresolved {
+			t.Errorf("unexpected issue: %s", issue)
+		}
+
+		unresolved[issue.Path] = true
+	}
+
+	if len(unresolved) != 2 || !unresolved["data\\missing.bin"] || !unresolved["stale\\missing.bin"] {
+		t.Errorf("unresolved listfile entries: %v", unresolved)
+	}
+}
@@ -0,0 +1,406 @@
+package mpq
+
+import (
+	"container/heap"
+	"sort"
+)
+
+const (
+	bzip2BlockSize    = 720000
+	bzip2MaxCodeBits  = 17
+	bzip2GroupSize    = 50
+	bzip2TableCount   = 2
+	bzip2MaxRunLength = 4 + 255
+)
+
+var bzip2CrcTable = buildBzip2CrcTable()
+
+func buildBzip2CrcTable() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+
+		table[i] = crc
+	}
+
+	return table
+}
+
+func bzip2Crc(data []byte) uint32 {
+	crc := ^uint32(0)
+	for _, value := range data {
+		crc = crc<<8 ^ bzip2CrcTable[byte(crc>>24)^value]
+	}
+
+	return ^crc
+}
+
+type bzip2Writer struct {
+	data     []byte
+	bitBuff  uint64
+	bitCount uint
+}
+
+func (w *bzip2Writer) writeBits(value uint64, count uint) {
+	w.bitBuff = w.bitBuff<<count | value&(1<<count-1)
+	w.bitCount += count
+
+	for w.bitCount >= 8 {
+		w.data = append(w.data, byte(w.bitBuff>>(w.bitCount-8)))
+		w.bitCount -= 8
+	}
+}
+
+func (w *bzip2Writer) flush() []byte {
+	if w.bitCount > 0 {
+		w.data = append(w.data, byte(w.bitBuff<<(8-w.bitCount)))
+	}
+
+	w.bitBuff = 0
+	w.bitCount = 0
+
+	return w.data
+}
+
+func compressBzip2(data []byte) []byte {
+	w := &bzip2Writer{data: []byte{'B', 'Z', 'h', '9'}}
+
+	var streamCrc uint32
+	for offset := 0; offset < len(data); offset += bzip2BlockSize {
+		end := offset + bzip2BlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		blockCrc := bzip2Crc(data[offset:end])
+		streamCrc = (streamCrc<<1 | streamCrc>>31) ^ blockCrc
+
+		w.writeBits(0x314159, 24)
+		w.writeBits(0x265359, 24)
+		w.writeBits(uint64(blockCrc), 32)
+		w.writeBits(0, 1)
+		bzip2WriteBlock(w, bzip2RunLengthEncode(data[offset:end]))
+	}
+
+	w.writeBits(0x177245, 24)
+	w.writeBits(0x385090, 24)
+	w.writeBits(uint64(streamCrc), 32)
+
+	return w.flush()
+}
+
+func bzip2RunLengthEncode(data []byte) []byte {
+	var output []byte
+	for offset := 0; offset < len(data); {
+		length := 1
+		for offset+length < len(data) && length < bzip2MaxRunLength && data[offset+length] == data[offset] {
+			length++
+		}
+
+		if length >= 4 {
+			output = append(output, data[offset], data[offset], data[offset], data[offset], byte(length-4))
+		} else {
+			for i := 0; i < length; i++ {
+				output = append(output, data[offset])
+			}
+		}
+
+		offset += length
+	}
+
+	return output
+}
+
+func bzip2Transform(data []byte) ([]byte, int) {
+	var (
+		count    = len(data)
+		indices  = make([]int, count)
+		ranks    = make([]int, count)
+		newRanks = make([]int, count)
+	)
+
+	for i := range indices {
+		indices[i] = i
+		ranks[i] = int(data[i])
+	}
+
+	for step := 1; ; step <<= 1 {
+		less := func(a, b int) bool {
+			if ranks[a] != ranks[b] {
+				return ranks[a] < ranks[b]
+			}
+
+			return ranks[(a+step)%count] < ranks[(b+step)%count]
+		}
+
+		sort.Slice(indices, func(i, j int) bool {
+			return less(indices[i], indices[j])
+		})
+
+		newRanks[indices[0]] = 0
+		for i := 1; i < count; i++ {
+			newRanks[indices[i]] = newRanks[indices[i-1]]
+			if less(indices[i-1], indices[i]) {
+				newRanks[indices[i]]++
+			}
+		}
+
+		copy(ranks, newRanks)
+		if ranks[indices[count-1]] == count-1 || step >= count {
+			break
+		}
+	}
+
+	var (
+		output  = make([]byte, count)
+		origPtr int
+	)
+
+	for i, index := range indices {
+		if index == 0 {
+			origPtr = i
+		}
+
+		output[i] = data[(index+count-1)%count]
+	}
+
+	return output, origPtr
+}
+
+func bzip2WriteBlock(w *bzip2Writer, data []byte) {
+	transformed, origPtr := bzip2Transform(data)
+	w.writeBits(uint64(origPtr), 24)
+
+	var inUse [256]bool
+	for _, value := range data {
+		inUse[value] = true
+	}
+
+	var inUse16 uint64
+	for i := 0; i < 16; i++ {
+		for j := 0; j < 16; j++ {
+			if inUse[i*16+j] {
+				inUse16 |= 1 << uint(15-i)
+				break
+			}
+		}
+	}
+
+	w.writeBits(inUse16, 16)
+	for i := 0; i < 16; i++ {
+		if inUse16&(1<<uint(15-i)) == 0 {
+			continue
+		}
+
+		var bits uint64
+		for j := 0; j < 16; j++ {
+			if inUse[i*16+j] {
+				bits |= 1 << uint(15-j)
+			}
+		}
+
+		w.writeBits(bits, 16)
+	}
+
+	var symbolOrder []byte
+	for i, used := range inUse {
+		if used {
+			symbolOrder = append(symbolOrder, byte(i))
+		}
+	}
+
+	var (
+		symbols   []int
+		zeroRun   int
+		alphaSize = len(symbolOrder) + 2
+	)
+
+	flushRun := func() {
+		for zeroRun > 0 {
+			if zeroRun&1 != 0 {
+				symbols = append(symbols, 0)
+				zeroRun = (zeroRun - 1) / 2
+			} else {
+				symbols = append(symbols, 1)
+				zeroRun = (zeroRun - 2) / 2
+			}
+		}
+	}
+
+	for _, value := range transformed {
+		index := 0
+		for symbolOrder[index] != value {
+			index++
+		}
+
+		if index == 0 {
+			zeroRun++
+			continue
+		}
+
+		flushRun()
+		copy(symbolOrder[1:index+1], symbolOrder[:index])
+		symbolOrder[0] = value
+		symbols = append(symbols, index+1)
+	}
+
+	flushRun()
+	symbols = append(symbols, alphaSize-1)
+
+	frequencies := make([]int, alphaSize)
+	for _, symbol := range symbols {
+		frequencies[symbol]++
+	}
+
+	lengths := bzip2CodeLengths(frequencies)
+	codes := bzip2Codes(lengths)
+
+	selectorCount := (len(symbols) + bzip2GroupSize - 1) / bzip2GroupSize
+	w.writeBits(bzip2TableCount, 3)
+	w.writeBits(uint64(selectorCount), 15)
+	for i := 0; i < selectorCount; i++ {
+		w.writeBits(0, 1)
+	}
+
+	for i := 0; i < bzip2TableCount; i++ {
+		current := lengths[0]
+		w.writeBits(uint64(current), 5)
+		for _, length := range lengths {
+			for current < length {
+				w.writeBits(2, 2)
+				current++
+			}
+			for current > length {
+				w.writeBits(3, 2)
+				current--
+			}
+
+			w.writeBits(0, 1)
+		}
+	}
+
+	for _, symbol := range symbols {
+		w.writeBits(uint64(codes[symbol]), uint(lengths[symbol]))
+	}
+}
+
+type bzip2Node struct {
+	weight int
+	depth  int
+	symbol int
+	left   *bzip2Node
+	right  *bzip2Node
+}
+
+type bzip2NodeHeap []*bzip2Node
+
+func (h bzip2NodeHeap) Len() int {
+	return len(h)
+}
+
+func (h bzip2NodeHeap) Less(i, j int) bool {
+	if h[i].weight != h[j].weight {
+		return h[i].weight < h[j].weight
+	}
+
+	return h[i].depth < h[j].depth
+}
+
+func (h bzip2NodeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *bzip2NodeHeap) Push(x interface{}) {
+	*h = append(*h, x.(*bzip2Node))
+}
+
+func (h *bzip2NodeHeap) Pop() interface{} {
+	old := *h
+	node := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return node
+}
+
+func bzip2CodeLengths(frequencies []int) []int {
+	weights := make([]int, len(frequencies))
+	for i, frequency := range frequencies {
+		weights[i] = frequency + 1
+	}
+
+	for {
+		nodes := make(bzip2NodeHeap, len(weights))
+		for i, weight := range weights {
+			nodes[i] = &bzip2Node{weight: weight, symbol: i}
+		}
+
+		heap.Init(&nodes)
+		for nodes.Len() > 1 {
+			left := heap.Pop(&nodes).(*bzip2Node)
+			right := heap.Pop(&nodes).(*bzip2Node)
+
+			depth := left.depth
+			if right.depth > depth {
+				depth = right.depth
+			}
+
+			heap.Push(&nodes, &bzip2Node{weight: left.weight + right.weight, depth: depth + 1, left: left, right: right})
+		}
+
+		lengths := make([]int, len(weights))
+		var walk func(node *bzip2Node, depth int)
+		walk = func(node *bzip2Node, depth int) {
+			if node.left == nil {
+				lengths[node.symbol] = depth
+				return
+			}
+
+			walk(node.left, depth+1)
+			walk(node.right, depth+1)
+		}
+
+		walk(nodes[0], 0)
+
+		valid := true
+		for _, length := range lengths {
+			if length > bzip2MaxCodeBits {
+				valid = false
+				break
+			}
+		}
+
+		if valid {
+			return lengths
+		}
+
+		for i := range weights {
+			weights[i] = weights[i]/2 + 1
+		}
+	}
+}
+
+func bzip2Codes(lengths []int) []int {
+	var (
+		codes = make([]int, len(lengths))
+		code  int
+	)
+
+	for length := 1; length <= bzip2MaxCodeBits; length++ {
+		for symbol, symbolLength := range lengths {
+			if symbolLength == length {
+				codes[symbol] = code
+				code++
+			}
+		}
+
+		code <<= 1
+	}
+
+	return codes
+}
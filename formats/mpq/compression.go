@@ -0,0 +1,102 @@
+package mpq
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/zlib"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+const (
+	compressionHuffman     = 0x01
+	compressionZlib        = 0x02
+	compressionImplode     = 0x08
+	compressionBzip2       = 0x10
+	compressionAdpcmMono   = 0x40
+	compressionAdpcmStereo = 0x80
+)
+
+func decompress(data []byte, size int) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("compressed data is empty")
+	}
+
+	mask := data[0]
+	data = data[1:]
+
+	if mask&^(compressionHuffman|compressionZlib|compressionImplode|compressionBzip2|compressionAdpcmMono|compressionAdpcmStereo) != 0 {
+		return nil, errors.New("unsupported compression type")
+	}
+
+	var err error
+	if mask&compressionBzip2 != 0 {
+		if data, err = ioutil.ReadAll(io.LimitReader(bzip2.NewReader(bytes.NewReader(data)), int64(size))); err != nil {
+			return nil, err
+		}
+	}
+
+	if mask&compressionImplode != 0 {
+		if data, err = explode(data, size); err != nil {
+			return nil, err
+		}
+	}
+
+	if mask&compressionZlib != 0 {
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		data, err = ioutil.ReadAll(io.LimitReader(reader, int64(size)))
+		reader.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if mask&compressionHuffman != 0 {
+		if data, err = decompressHuffman(data, size); err != nil {
+			return nil, err
+		}
+	}
+
+	if mask&compressionAdpcmStereo != 0 {
+		data = decompressAdpcm(data, size, 2)
+	}
+
+	if mask&compressionAdpcmMono != 0 {
+		data = decompressAdpcm(data, size, 1)
+	}
+
+	return data, nil
+}
+
+func compress(data []byte, mask byte) ([]byte, error) {
+	var (
+		buff   bytes.Buffer
+		output []byte
+	)
+
+	switch mask {
+	case compressionZlib:
+		writer := zlib.NewWriter(&buff)
+		if _, err := writer.Write(data); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+
+		output = buff.Bytes()
+	case compressionBzip2:
+		output = compressBzip2(data)
+	case compressionImplode:
+		output = implode(data)
+	default:
+		return nil, errors.New("unsupported compression type")
+	}
+
+	return append([]byte{mask}, output...), nil
+}
@@ -0,0 +1,183 @@
+//go:build !stormlib
+// +build !stormlib
+
+package mpq
+
+import (
+	"crypto/md5"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+	"strings"
+)
+
+func (a *Archive) HashTableSize() int {
+	return len(a.hashTable)
+}
+
+func (w *Writer) CopyFile(a *Archive, path string) error {
+	if w.file == nil {
+		return errors.New("writer is not open")
+	}
+
+	a.pathMutex.RLock()
+	defer a.pathMutex.RUnlock()
+
+	if pathInt, ok := a.paths[path]; ok {
+		path = pathInt
+	}
+
+	if strings.HasPrefix(path, "(") {
+		return errors.New("reserved file name")
+	}
+
+	var (
+		sameLayout   = len(a.hashTable) == len(w.hashTable)
+		hash, noName = a.unnamed[path]
+		copied       bool
+	)
+
+	if !noName {
+		hash = hashEntry{NameA: hashString(path, hashTypeNameA), NameB: hashString(path, hashTypeNameB)}
+	} else if !sameLayout {
+		return errors.New("unnamed file requires a hash table of the original size")
+	}
+
+	if sameLayout && w.copySource != a {
+		// keep removed entries as deleted so that probing still reaches the copied ones
+		for i, source := range a.hashTable {
+			if source.BlockIndex != hashEntryEmpty && w.hashTable[i].BlockIndex == hashEntryEmpty {
+				w.hashTable[i] = hashEntry{hashEntryEmpty, hashEntryEmpty, 0xffff, 0xffff, hashEntryDeleted}
+			}
+		}
+
+		w.copySource = a
+	}
+
+	for i, source := range a.hashTable {
+		if source.BlockIndex == hashEntryEmpty || source.BlockIndex == hashEntryDeleted || int(source.BlockIndex) >= len(a.blockTable) {
+			continue
+		}
+
+		if source.NameA != hash.NameA || source.NameB != hash.NameB || noName && source.BlockIndex != hash.BlockIndex {
+			continue
+		}
+
+		block := a.blockTable[source.BlockIndex]
+		if block.Flags&fileExists == 0 || block.Flags&fileDeleteMarker != 0 {
+			continue
+		}
+
+		hashIndex := i
+		if !sameLayout {
+			var err error
+			if hashIndex, err = w.findCopyHashEntry(path, source); err != nil {
+				return err
+			}
+		} else if w.hashTable[i].BlockIndex != hashEntryDeleted {
+			return errors.New("hash table entry is already in use")
+		}
+
+		name := path
+		if noName {
+			name = ""
+		}
+
+		if err := w.copyBlock(a, block, name); err != nil {
+			return err
+		}
+
+		w.hashTable[hashIndex] = hashEntry{source.NameA, source.NameB, source.Locale, source.Platform, uint32(len(w.blockTable) - 1)}
+		copied = true
+	}
+
+	if !copied {
+		return errors.New("file not found in archive")
+	}
+
+	if !noName {
+		w.paths = append(w.paths, path)
+	}
+
+	return nil
+}
+
+func (w *Writer) copyBlock(a *Archive, block blockEntry, name string) error {
+	if block.Flags&fileSingleUnit == 0 && block.FileSize > 0 && a.sectorSize() != 0x200<<writerSectorSizeShift {
+		return errors.New("archive sector size does not match writer")
+	}
+
+	file, err := a.openBlock(block, name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+
+	raw := make([]byte, block.CompressedSize)
+	if _, err := a.file.ReadAt(raw, a.offset+int64(block.FilePos)); err != nil {
+		return err
+	}
+
+	copyBlock := block
+	copyBlock.FilePos = w.offset
+
+	if block.Flags&fileEncrypted != 0 && block.Flags&fileFixKey != 0 && copyBlock.FilePos != block.FilePos {
+		var (
+			oldKey = file.key
+			newKey = ((oldKey ^ block.FileSize) - block.FilePos + copyBlock.FilePos) ^ block.FileSize
+		)
+
+		rekey := func(data []byte, oldKey, newKey uint32) {
+			decryptBytes(data, oldKey)
+			encryptBytes(data, newKey)
+		}
+
+		if block.Flags&fileSingleUnit != 0 {
+			rekey(raw, oldKey, newKey)
+		} else {
+			if block.Flags&(fileCompress|fileImplode) != 0 {
+				rekey(raw[:len(file.sectorOffsets)*4], oldKey-1, newKey-1)
+			}
+
+			for i := 0; i < file.sectorCount(); i++ {
+				rekey(raw[file.sectorOffsets[i]:file.sectorOffsets[i+1]], oldKey+uint32(i), newKey+uint32(i))
+			}
+		}
+	}
+
+	if err := w.write(raw); err != nil {
+		return err
+	}
+
+	w.blockTable = append(w.blockTable, copyBlock)
+	w.checksums = append(w.checksums, writerChecksum{crc32.ChecksumIEEE(data), md5.Sum(data)})
+
+	return nil
+}
+
+func (w *Writer) findCopyHashEntry(path string, source hashEntry) (int, error) {
+	var (
+		mask  = uint32(len(w.hashTable) - 1)
+		start = hashString(path, hashTypeOffset) & mask
+	)
+
+	for i := start; ; {
+		hash := w.hashTable[i]
+		if hash.BlockIndex == hashEntryEmpty {
+			return int(i), nil
+		}
+
+		if hash.NameA == source.NameA && hash.NameB == source.NameB && hash.Locale == source.Locale {
+			return 0, errors.New("file already exists in archive")
+		}
+
+		if i = (i + 1) & mask; i == start {
+			return 0, errors.New("archive hash table is full")
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package mpq
+
+import (
+	"encoding/binary"
+	"strings"
+)
+
+const (
+	hashTypeOffset = iota
+	hashTypeNameA
+	hashTypeNameB
+	hashTypeFileKey
+	hashTypeTable
+)
+
+var cryptTable = buildCryptTable()
+
+func buildCryptTable() [0x500]uint32 {
+	var (
+		table [0x500]uint32
+		seed  = uint32(0x00100001)
+	)
+
+	for i := 0; i < 0x100; i++ {
+		for j := i; j < len(table); j += 0x100 {
+			seed = (seed*125 + 3) % 0x2aaaab
+			high := (seed & 0xffff) << 0x10
+			seed = (seed*125 + 3) % 0x2aaaab
+			low := seed & 0xffff
+			table[j] = high | low
+		}
+	}
+
+	return table
+}
+
+func hashString(value string, hashType int) uint32 {
+	var (
+		seed1 = uint32(0x7fed7fed)
+		seed2 = uint32(0xeeeeeeee)
+	)
+
+	for _, c := range []byte(strings.ToUpper(strings.Replace(value, "/", "\\", -1))) {
+		seed1 = cryptTable[hashType<<8+int(c)] ^ (seed1 + seed2)
+		seed2 = uint32(c) + seed1 + seed2 + (seed2 << 5) + 3
+	}
+
+	return seed1
+}
+
+func decryptBlock(data []uint32, key uint32) {
+	seed := uint32(0xeeeeeeee)
+	for i, value := range data {
+		seed += cryptTable[hashTypeTable<<8+int(key&0xff)]
+		value ^= key + seed
+		key = ((^key << 0x15) + 0x11111111) | (key >> 0x0b)
+		seed = value + seed + (seed << 5) + 3
+		data[i] = value
+	}
+}
+
+func encryptBlock(data []uint32, key uint32) {
+	seed := uint32(0xeeeeeeee)
+	for i, value := range data {
+		seed += cryptTable[hashTypeTable<<8+int(key&0xff)]
+		data[i] = value ^ (key + seed)
+		key = ((^key << 0x15) + 0x11111111) | (key >> 0x0b)
+		seed = value + seed + (seed << 5) + 3
+	}
+}
+
+func decryptBytes(data []byte, key uint32) {
+	words := make([]uint32, len(data)/4)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+
+	decryptBlock(words, key)
+
+	for i, word := range words {
+		binary.LittleEndian.PutUint32(data[i*4:], word)
+	}
+}
+
+func encryptBytes(data []byte, key uint32) {
+	words := make([]uint32, len(data)/4)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint32(data[i*4:])
+	}
+
+	encryptBlock(words, key)
+
+	for i, word := range words {
+		binary.LittleEndian.PutUint32(data[i*4:], word)
+	}
+}
+
+func detectKey(encrypted []uint32, decrypted, limit uint32) (uint32, bool) {
+	temp := (encrypted[0] ^ decrypted) - 0xeeeeeeee
+	for i := 0; i < 0x100; i++ {
+		key := temp - cryptTable[hashTypeTable<<8+i]
+		if key&0xff != uint32(i) {
+			continue
+		}
+
+		data := []uint32{encrypted[0], encrypted[1]}
+		decryptBlock(data, key)
+		if data[0] == decrypted && data[1] >= decrypted && data[1]-decrypted <= limit {
+			return key, true
+		}
+	}
+
+	return 0, false
+}
+
+func fileKey(path string, block blockEntry) uint32 {
+	if index := strings.LastIndexAny(path, "\\/"); index >= 0 {
+		path = path[index+1:]
+	}
+
+	key := hashString(path, hashTypeFileKey)
+	if block.Flags&fileFixKey != 0 {
+		key = (key + block.FilePos) ^ block.FileSize
+	}
+
+	return key
+}
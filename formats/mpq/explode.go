@@ -0,0 +1,161 @@
+package mpq
+
+import "errors"
+
+const explodeMaxBits = 13
+
+type explodeHuffman struct {
+	counts  [explodeMaxBits + 1]int
+	symbols []int
+}
+
+var (
+	explodeLiteralCode  = newExplodeHuffman([]byte{11, 124, 8, 7, 28, 7, 188, 13, 76, 4, 10, 8, 12, 10, 12, 10, 8, 23, 8, 9, 7, 6, 7, 8, 7, 6, 55, 8, 23, 24, 12, 11, 7, 9, 11, 12, 6, 7, 22, 5, 7, 24, 6, 11, 9, 6, 7, 22, 7, 11, 38, 7, 9, 8, 25, 11, 8, 11, 9, 12, 8, 12, 5, 38, 5, 38, 5, 11, 7, 5, 6, 21, 6, 10, 53, 8, 7, 24, 10, 27, 44, 253, 253, 253, 252, 252, 252, 13, 12, 45, 12, 45, 12, 61, 12, 45, 44, 173})
+	explodeLengthCode   = newExplodeHuffman([]byte{2, 35, 36, 53, 38, 23})
+	explodeDistanceCode = newExplodeHuffman([]byte{2, 20, 53, 230, 247, 151, 248})
+	explodeLengthBase   = [16]int{3, 2, 4, 5, 6, 7, 8, 9, 10, 12, 16, 24, 40, 72, 136, 264}
+	explodeLengthExtra  = [16]int{0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 4, 5, 6, 7, 8}
+)
+
+func newExplodeHuffman(lengthsPacked []byte) *explodeHuffman {
+	var lengths []int
+	for _, value := range lengthsPacked {
+		for i := 0; i < int(value>>4)+1; i++ {
+			lengths = append(lengths, int(value&0x0f))
+		}
+	}
+
+	h := &explodeHuffman{symbols: make([]int, len(lengths))}
+	for _, length := range lengths {
+		h.counts[length]++
+	}
+
+	var offsets [explodeMaxBits + 1]int
+	for length := 1; length < explodeMaxBits; length++ {
+		offsets[length+1] = offsets[length] + h.counts[length]
+	}
+
+	for symbol, length := range lengths {
+		if length != 0 {
+			h.symbols[offsets[length]] = symbol
+			offsets[length]++
+		}
+	}
+
+	return h
+}
+
+type explodeReader struct {
+	data     []byte
+	offset   int
+	bitBuff  uint
+	bitCount uint
+	err      error
+}
+
+func (r *explodeReader) readBits(count uint) int {
+	for r.bitCount < count {
+		if r.offset >= len(r.data) {
+			r.err = errors.New("imploded data is truncated")
+			return 0
+		}
+
+		r.bitBuff |= uint(r.data[r.offset]) << r.bitCount
+		r.bitCount += 8
+		r.offset++
+	}
+
+	value := int(r.bitBuff & (1<<count - 1))
+	r.bitBuff >>= count
+	r.bitCount -= count
+
+	return value
+}
+
+func (r *explodeReader) decode(h *explodeHuffman) int {
+	var code, first, index int
+	for length := 1; length <= explodeMaxBits; length++ {
+		code |= r.readBits(1) ^ 1
+		if r.err != nil {
+			return 0
+		}
+
+		count := h.counts[length]
+		if code-count < first {
+			return h.symbols[index+code-first]
+		}
+
+		index += count
+		first += count
+		first <<= 1
+		code <<= 1
+	}
+
+	r.err = errors.New("invalid imploded code")
+	return 0
+}
+
+func explode(data []byte, size int) ([]byte, error) {
+	r := &explodeReader{data: data}
+
+	literalCoded := r.readBits(8)
+	dictBits := r.readBits(8)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if literalCoded > 1 {
+		return nil, errors.New("invalid imploded literal flag")
+	}
+	if dictBits < 4 || dictBits > 6 {
+		return nil, errors.New("invalid imploded dictionary size")
+	}
+
+	output := make([]byte, 0, size)
+	for len(output) < size {
+		if r.readBits(1) != 0 {
+			symbol := r.decode(explodeLengthCode)
+			length := explodeLengthBase[symbol] + r.readBits(uint(explodeLengthExtra[symbol]))
+			if length == 519 {
+				break
+			}
+
+			distBits := dictBits
+			if length == 2 {
+				distBits = 2
+			}
+
+			distance := r.decode(explodeDistanceCode)<<uint(distBits) + r.readBits(uint(distBits)) + 1
+			if r.err != nil {
+				return nil, r.err
+			}
+
+			if distance > len(output) {
+				return nil, errors.New("invalid imploded distance")
+			}
+
+			for i := 0; i < length; i++ {
+				output = append(output, output[len(output)-distance])
+			}
+		} else {
+			var literal int
+			if literalCoded != 0 {
+				literal = r.decode(explodeLiteralCode)
+			} else {
+				literal = r.readBits(8)
+			}
+
+			output = append(output, byte(literal))
+		}
+
+		if r.err != nil {
+			return nil, r.err
+		}
+	}
+
+	if len(output) > size {
+		output = output[:size]
+	}
+
+	return output, nil
+}
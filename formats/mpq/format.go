@@ -0,0 +1,62 @@
+package mpq
+
+const (
+	fileImplode      = 0x00000100
+	fileCompress     = 0x00000200
+	fileEncrypted    = 0x00010000
+	fileFixKey       = 0x00020000
+	fileSingleUnit   = 0x01000000
+	fileDeleteMarker = 0x02000000
+	fileSectorCrc    = 0x04000000
+	fileExists       = 0x80000000
+)
+
+const (
+	attributesVersion  = 100
+	attributesCrc32    = 0x00000001
+	attributesFileTime = 0x00000002
+	attributesMd5      = 0x00000004
+)
+
+const (
+	hashEntryEmpty   = 0xffffffff
+	hashEntryDeleted = 0xfffffffe
+)
+
+var (
+	archiveSignature  = [4]byte{'M', 'P', 'Q', 0x1a}
+	userDataSignature = [4]byte{'M', 'P', 'Q', 0x1b}
+)
+
+type archiveHeader struct {
+	Signature       [4]byte
+	HeaderSize      uint32
+	ArchiveSize     uint32
+	FormatVersion   uint16
+	SectorSizeShift uint16
+	HashTablePos    uint32
+	BlockTablePos   uint32
+	HashTableSize   uint32
+	BlockTableSize  uint32
+}
+
+type userDataHeader struct {
+	Signature    [4]byte
+	UserDataSize uint32
+	HeaderOffset uint32
+}
+
+type hashEntry struct {
+	NameA      uint32
+	NameB      uint32
+	Locale     uint16
+	Platform   uint16
+	BlockIndex uint32
+}
+
+type blockEntry struct {
+	FilePos        uint32
+	CompressedSize uint32
+	FileSize       uint32
+	Flags          uint32
+}
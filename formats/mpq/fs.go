@@ -0,0 +1,119 @@
+package mpq
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+
+	"github.com/FooSoft/lazarus/vfs"
+)
+
+func (a *Archive) Open(name string) (fs.File, error) {
+	a.pathMutex.RLock()
+	defer a.pathMutex.RUnlock()
+
+	if !vfs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	name = strings.ToLower(name)
+	if entries, ok := a.dirs[name]; ok {
+		dirEntries, err := vfs.ReadDir(name, entries, a.statPath)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		return vfs.NewDir(name, dirEntries), nil
+	}
+
+	if _, ok := a.paths[filepath.FromSlash(name)]; !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	file, err := a.openFile(filepath.FromSlash(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &fsFile{file, vfs.NewFileInfo(name, file.Size())}, nil
+}
+
+func (a *Archive) ReadDir(name string) ([]fs.DirEntry, error) {
+	a.pathMutex.RLock()
+	defer a.pathMutex.RUnlock()
+
+	if !vfs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	name = strings.ToLower(name)
+	entries, ok := a.dirs[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	dirEntries, err := vfs.ReadDir(name, entries, a.statPath)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	return dirEntries, nil
+}
+
+func (a *Archive) Stat(name string) (fs.FileInfo, error) {
+	a.pathMutex.RLock()
+	defer a.pathMutex.RUnlock()
+
+	if !vfs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := a.statPath(strings.ToLower(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	return info, nil
+}
+
+func (a *Archive) Glob(pattern string) ([]string, error) {
+	a.pathMutex.RLock()
+	defer a.pathMutex.RUnlock()
+
+	return vfs.Glob(a.dirs, strings.ToLower(pattern))
+}
+
+func (a *Archive) buildDirMap() {
+	var names []string
+	for pathExt := range a.paths {
+		names = append(names, filepath.ToSlash(pathExt))
+	}
+
+	a.dirs = vfs.BuildDirs(names)
+}
+
+func (a *Archive) statPath(name string) (fs.FileInfo, error) {
+	if _, ok := a.dirs[name]; ok {
+		return vfs.NewDirInfo(name), nil
+	}
+
+	if _, ok := a.paths[filepath.FromSlash(name)]; !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	size, err := a.fileSize(filepath.FromSlash(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return vfs.NewFileInfo(name, size), nil
+}
+
+type fsFile struct {
+	*File
+	info vfs.FileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
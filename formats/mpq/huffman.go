@@ -0,0 +1,306 @@
+package mpq
+
+import "errors"
+
+const (
+	huffmanItemCount = 0x203
+	huffmanEnd       = 0x100
+	huffmanNewByte   = 0x101
+)
+
+var huffmanWeights = [9][]byte{
+	0: {
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+		0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a, 0x0a,
+	},
+	1: {
+		0x54, 0x16, 0x16, 0x0d, 0x0c, 0x08, 0x06, 0x05, 0x06, 0x05, 0x06, 0x03, 0x04, 0x04, 0x03, 0x05,
+		0x0e, 0x0b, 0x14, 0x13, 0x13, 0x09, 0x0b, 0x06, 0x05, 0x04, 0x03, 0x02, 0x03, 0x02, 0x02, 0x02,
+		0x0d, 0x07, 0x09, 0x06, 0x06, 0x04, 0x03, 0x02, 0x04, 0x03, 0x03, 0x03, 0x03, 0x03, 0x02, 0x02,
+		0x09, 0x06, 0x04, 0x04, 0x04, 0x04, 0x03, 0x02, 0x03, 0x02, 0x02, 0x02, 0x02, 0x03, 0x02, 0x04,
+		0x08, 0x03, 0x04, 0x07, 0x09, 0x05, 0x03, 0x03, 0x03, 0x03, 0x02, 0x02, 0x02, 0x03, 0x02, 0x02,
+		0x03, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x01, 0x01, 0x01, 0x02, 0x01, 0x02, 0x02,
+		0x06, 0x0a, 0x08, 0x08, 0x06, 0x07, 0x04, 0x03, 0x04, 0x04, 0x02, 0x02, 0x04, 0x02, 0x03, 0x03,
+		0x04, 0x03, 0x07, 0x07, 0x09, 0x06, 0x04, 0x03, 0x03, 0x02, 0x01, 0x02, 0x02, 0x02, 0x02, 0x02,
+		0x0a, 0x02, 0x02, 0x03, 0x02, 0x02, 0x01, 0x01, 0x02, 0x02, 0x02, 0x06, 0x03, 0x05, 0x02, 0x03,
+		0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x02, 0x03, 0x01,
+		0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01,
+		0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01,
+		0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01,
+		0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01,
+		0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01,
+		0x02, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x01, 0x4b,
+	},
+	4: {
+		0xff, 0xfb, 0x98, 0x9a, 0x84, 0x85, 0x63, 0x64, 0x3e, 0x3e, 0x22, 0x22, 0x13, 0x13, 0x18, 0x17,
+	},
+	5: {
+		0xff, 0xf1, 0x9d, 0x9e, 0x9a, 0x9b, 0x9a, 0x97, 0x93, 0x93, 0x8c, 0x8e, 0x86, 0x88, 0x80, 0x82,
+		0x7c, 0x7c, 0x72, 0x73, 0x69, 0x6b, 0x5f, 0x60, 0x55, 0x56, 0x4a, 0x4b, 0x40, 0x41, 0x37, 0x37,
+		0x2f, 0x2f, 0x27, 0x27, 0x21, 0x21, 0x1b, 0x1c, 0x17, 0x17, 0x13, 0x13, 0x10, 0x10, 0x0d, 0x0d,
+		0x0b, 0x0b, 0x09, 0x09, 0x08, 0x08, 0x07, 0x07, 0x06, 0x05, 0x05, 0x04, 0x04, 0x04, 0x19, 0x18,
+	},
+}
+
+type huffmanItem struct {
+	next    *huffmanItem
+	prev    *huffmanItem
+	value   int
+	weight  int
+	parent  *huffmanItem
+	childLo *huffmanItem
+}
+
+type huffmanTree struct {
+	head    huffmanItem
+	items   [huffmanItemCount]huffmanItem
+	used    int
+	byValue [huffmanNewByte + 1]*huffmanItem
+}
+
+func newHuffmanTree(compressionType int) (*huffmanTree, error) {
+	if compressionType >= len(huffmanWeights) || huffmanWeights[compressionType] == nil {
+		return nil, errors.New("unsupported huffman compression type")
+	}
+
+	t := new(huffmanTree)
+	t.head.next = &t.head
+	t.head.prev = &t.head
+
+	for value, weight := range huffmanWeights[compressionType] {
+		if weight != 0 {
+			item := t.newItem(value, int(weight))
+			t.insertAfter(item, t.findHigherOrEqual(t.head.prev, item.weight))
+			t.byValue[value] = item
+		}
+	}
+
+	for _, value := range []int{huffmanEnd, huffmanNewByte} {
+		item := t.newItem(value, 1)
+		t.insertBefore(item, &t.head)
+		t.byValue[value] = item
+	}
+
+	for lo := t.head.prev; lo != &t.head; {
+		hi := lo.prev
+		if hi == &t.head {
+			break
+		}
+
+		parent := t.newItem(0, hi.weight+lo.weight)
+		lo.parent = parent
+		hi.parent = parent
+		parent.childLo = lo
+		t.insertAfter(parent, t.findHigherOrEqual(t.head.prev, parent.weight))
+
+		lo = hi.prev
+	}
+
+	return t, nil
+}
+
+func (t *huffmanTree) newItem(value, weight int) *huffmanItem {
+	if t.used >= len(t.items) {
+		return nil
+	}
+
+	item := &t.items[t.used]
+	item.value = value
+	item.weight = weight
+	t.used++
+
+	return item
+}
+
+func (t *huffmanTree) remove(item *huffmanItem) {
+	if item.next != nil {
+		item.prev.next = item.next
+		item.next.prev = item.prev
+		item.next = nil
+		item.prev = nil
+	}
+}
+
+func (t *huffmanTree) insertAfter(item, point *huffmanItem) {
+	t.remove(item)
+	item.next = point.next
+	item.prev = point
+	point.next.prev = item
+	point.next = item
+}
+
+func (t *huffmanTree) insertBefore(item, point *huffmanItem) {
+	t.remove(item)
+	item.next = point
+	item.prev = point.prev
+	point.prev.next = item
+	point.prev = item
+}
+
+func (t *huffmanTree) findHigherOrEqual(item *huffmanItem, weight int) *huffmanItem {
+	for ; item != &t.head; item = item.prev {
+		if item.weight >= weight {
+			return item
+		}
+	}
+
+	return &t.head
+}
+
+func (t *huffmanTree) incWeights(item *huffmanItem) {
+	for ; item != nil; item = item.parent {
+		item.weight++
+
+		higher := t.findHigherOrEqual(item.prev, item.weight)
+		swap := higher.next
+		if swap == item {
+			continue
+		}
+
+		t.insertBefore(swap, item)
+		t.insertAfter(item, higher)
+
+		swapLo := swap.parent.childLo
+		if item.parent.childLo == item {
+			item.parent.childLo = swap
+		}
+		if swapLo == swap {
+			swap.parent.childLo = item
+		}
+
+		item.parent, swap.parent = swap.parent, item.parent
+	}
+}
+
+func (t *huffmanTree) insertBranch(lastValue, newValue int) error {
+	if t.byValue[newValue] != nil {
+		return errors.New("invalid huffman data")
+	}
+
+	last := t.head.prev
+	hi := t.newItem(lastValue, last.weight)
+	lo := t.newItem(newValue, 0)
+	if hi == nil || lo == nil {
+		return errors.New("invalid huffman data")
+	}
+
+	t.insertBefore(hi, &t.head)
+	hi.parent = last
+	t.byValue[lastValue] = hi
+
+	t.insertBefore(lo, &t.head)
+	lo.parent = last
+	last.childLo = lo
+	t.byValue[newValue] = lo
+
+	t.incWeights(lo)
+	return nil
+}
+
+type huffmanReader struct {
+	data     []byte
+	offset   int
+	bitBuff  uint
+	bitCount uint
+	err      error
+}
+
+func (r *huffmanReader) readBits(count uint) int {
+	for r.bitCount < count {
+		if r.offset >= len(r.data) {
+			r.err = errors.New("huffman data is truncated")
+			return 0
+		}
+
+		r.bitBuff |= uint(r.data[r.offset]) << r.bitCount
+		r.bitCount += 8
+		r.offset++
+	}
+
+	value := int(r.bitBuff & (1<<count - 1))
+	r.bitBuff >>= count
+	r.bitCount -= count
+
+	return value
+}
+
+func (r *huffmanReader) decode(t *huffmanTree) int {
+	item := t.head.next
+	for item.childLo != nil {
+		if r.readBits(1) != 0 {
+			item = item.childLo.prev
+		} else {
+			item = item.childLo
+		}
+
+		if r.err != nil {
+			return 0
+		}
+	}
+
+	return item.value
+}
+
+func decompressHuffman(data []byte, size int) ([]byte, error) {
+	r := &huffmanReader{data: data}
+
+	compressionType := r.readBits(8)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	t, err := newHuffmanTree(compressionType)
+	if err != nil {
+		return nil, err
+	}
+
+	adaptive := compressionType == 0
+
+	output := make([]byte, 0, size)
+	for len(output) < size {
+		value := r.decode(t)
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		if value == huffmanEnd {
+			break
+		}
+
+		if value == huffmanNewByte {
+			if value = r.readBits(8); r.err != nil {
+				return nil, r.err
+			}
+
+			if err := t.insertBranch(t.head.prev.value, value); err != nil {
+				return nil, err
+			}
+
+			if !adaptive {
+				t.incWeights(t.byValue[value])
+			}
+		}
+
+		output = append(output, byte(value))
+
+		if adaptive {
+			t.incWeights(t.byValue[value])
+		}
+	}
+
+	return output, nil
+}
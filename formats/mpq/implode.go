@@ -0,0 +1,169 @@
+package mpq
+
+const (
+	implodeDictBits    = 6
+	implodeMinMatch    = 3
+	implodeMaxMatch    = 518
+	implodeMaxDistance = 64 << implodeDictBits
+	implodeHashSize    = 1 << 12
+	implodeChainDepth  = 64
+)
+
+type implodeCode struct {
+	codes   []int
+	lengths []int
+}
+
+var (
+	implodeLengthCode   = newImplodeCode(explodeLengthCode)
+	implodeDistanceCode = newImplodeCode(explodeDistanceCode)
+)
+
+func newImplodeCode(h *explodeHuffman) *implodeCode {
+	c := &implodeCode{
+		codes:   make([]int, len(h.symbols)),
+		lengths: make([]int, len(h.symbols)),
+	}
+
+	var code, index int
+	for length := 1; length <= explodeMaxBits; length++ {
+		for i := 0; i < h.counts[length]; i++ {
+			symbol := h.symbols[index]
+			c.codes[symbol] = code
+			c.lengths[symbol] = length
+			code++
+			index++
+		}
+
+		code <<= 1
+	}
+
+	return c
+}
+
+type implodeWriter struct {
+	data     []byte
+	bitBuff  uint
+	bitCount uint
+}
+
+func (w *implodeWriter) writeBits(value int, count uint) {
+	w.bitBuff |= uint(value) & (1<<count - 1) << w.bitCount
+	w.bitCount += count
+
+	for w.bitCount >= 8 {
+		w.data = append(w.data, byte(w.bitBuff))
+		w.bitBuff >>= 8
+		w.bitCount -= 8
+	}
+}
+
+func (w *implodeWriter) writeCode(c *implodeCode, symbol int) {
+	for bit := c.lengths[symbol] - 1; bit >= 0; bit-- {
+		w.writeBits((c.codes[symbol]>>uint(bit))&1^1, 1)
+	}
+}
+
+func (w *implodeWriter) writeLength(length int) {
+	var symbol int
+	for i, base := range explodeLengthBase {
+		if length >= base && length < base+1<<uint(explodeLengthExtra[i]) {
+			symbol = i
+			break
+		}
+	}
+
+	w.writeBits(1, 1)
+	w.writeCode(implodeLengthCode, symbol)
+	w.writeBits(length-explodeLengthBase[symbol], uint(explodeLengthExtra[symbol]))
+}
+
+func (w *implodeWriter) flush() []byte {
+	if w.bitCount > 0 {
+		w.data = append(w.data, byte(w.bitBuff))
+	}
+
+	w.bitBuff = 0
+	w.bitCount = 0
+
+	return w.data
+}
+
+func implode(data []byte) []byte {
+	w := &implodeWriter{data: []byte{0, implodeDictBits}}
+
+	var (
+		head  = make([]int, implodeHashSize)
+		chain = make([]int, len(data))
+	)
+
+	for i := range head {
+		head[i] = -1
+	}
+
+	hashAt := func(offset int) int {
+		return (int(data[offset])<<8 ^ int(data[offset+1])<<4 ^ int(data[offset+2])) & (implodeHashSize - 1)
+	}
+
+	insert := func(offset int) {
+		if offset+implodeMinMatch <= len(data) {
+			hash := hashAt(offset)
+			chain[offset] = head[hash]
+			head[hash] = offset
+		}
+	}
+
+	for offset := 0; offset < len(data); {
+		var bestLength, bestDistance int
+		if offset+implodeMinMatch <= len(data) {
+			maxLength := len(data) - offset
+			if maxLength > implodeMaxMatch {
+				maxLength = implodeMaxMatch
+			}
+
+			candidate := head[hashAt(offset)]
+			for depth := 0; candidate >= 0 && depth < implodeChainDepth; depth++ {
+				distance := offset - candidate
+				if distance > implodeMaxDistance {
+					break
+				}
+
+				var length int
+				for length < maxLength && data[candidate+length] == data[offset+length] {
+					length++
+				}
+
+				if length > bestLength {
+					bestLength = length
+					bestDistance = distance
+					if length == maxLength {
+						break
+					}
+				}
+
+				candidate = chain[candidate]
+			}
+		}
+
+		if bestLength >= implodeMinMatch {
+			w.writeLength(bestLength)
+			w.writeCode(implodeDistanceCode, (bestDistance-1)>>implodeDictBits)
+			w.writeBits(bestDistance-1, implodeDictBits)
+
+			for i := 0; i < bestLength; i++ {
+				insert(offset + i)
+			}
+
+			offset += bestLength
+		} else {
+			w.writeBits(0, 1)
+			w.writeBits(int(data[offset]), 8)
+
+			insert(offset)
+			offset++
+		}
+	}
+
+	w.writeLength(519)
+	return w.flush()
+}
@@ -0,0 +1,416 @@
+package mpq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestHashString(t *testing.T) {
+	test := func(value string, hashType int, expected uint32) {
+		if result := hashString(value, hashType); result != expected {
+			t.Errorf("hash of %q (%d): %.8x, expected: %.8x", value, hashType, result, expected)
+		}
+	}
+
+	test("(hash table)", hashTypeFileKey, 0xc3af3770)
+	test("(block table)", hashTypeFileKey, 0xec83b3a3)
+}
+
+func TestExplode(t *testing.T) {
+	var (
+		data     = []byte{0x00, 0x04, 0x82, 0x24, 0x25, 0x8f, 0x80, 0x7f}
+		expected = []byte("AIAIAIAIAIAIA")
+	)
+
+	result, err := explode(data, len(expected))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(result, expected) {
+		t.Errorf("result: %q, expected: %q", result, expected)
+	}
+}
+
+func testCompressionData() []byte {
+	var data []byte
+	for i := 0; i < 10000; i++ {
+		data = append(data, byte(i%7), byte(i%13), 'x', 'x', 'x', 'x', 'x', byte(i>>3))
+	}
+
+	return data
+}
+
+func TestHuffman(t *testing.T) {
+	test := func(data, expected []byte) {
+		result, err := decompressHuffman(data, len(expected))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(result, expected) {
+			t.Errorf("result: %q, expected: %q", result, expected)
+		}
+
+		if _, err := decompressHuffman(data[:len(data)/2], len(expected)); err == nil {
+			t.Error("truncated data was accepted")
+		}
+	}
+
+	test(
+		[]byte{
+			0x00, 0x9d, 0x85, 0x7f, 0xbf, 0x7f, 0xbf, 0xff, 0x7f, 0xff, 0x7f, 0xff, 0x7f, 0xff, 0x79, 0xd2,
+			0x52, 0xf2, 0x12, 0xb2, 0x3f, 0x62, 0xae, 0x22, 0x2f, 0xef, 0x9a, 0x32, 0x3f, 0xff, 0xff, 0xff,
+			0xff, 0x8f, 0xc7, 0xe7, 0xf3, 0xfd, 0x7d, 0x09,
+		},
+		[]byte("AIAIAIAIAIAIA storm huffman AAAAAAAAAA"),
+	)
+
+	test(
+		[]byte{
+			0x01, 0x9e, 0xc9, 0x09, 0xd6, 0x59, 0x5a, 0x42, 0xb2, 0x5b, 0xd9, 0x87, 0xcb, 0xbe, 0xcf, 0xee,
+			0x85, 0x04, 0x92, 0x85, 0xc6, 0xf7, 0x64, 0x1f, 0x6e, 0xfb,
+		},
+		[]byte("(listfile)\r\ndata\\global\r\n"),
+	)
+
+	test(
+		[]byte{0x04, 0x2b, 0xe5, 0x02, 0xe4, 0x3a, 0xc8, 0x87, 0xbe, 0xfd, 0xd5, 0xb7, 0x6f, 0x9f, 0x00},
+		[]byte{0x00, 0x01, 0x02, 0x03, 0x40, 0x41, 0x40, 0x02, 0x7f, 0x01, 0x40, 0x40},
+	)
+
+	if _, err := decompressHuffman([]byte{0x09, 0x00}, 1); err == nil {
+		t.Error("invalid compression type was accepted")
+	}
+
+	result, err := decompress([]byte{compressionHuffman, 0x04, 0x2b, 0xe5, 0x02, 0xe4, 0x3a, 0xc8, 0x87, 0xbe, 0xfd, 0xd5, 0xb7, 0x6f, 0x9f, 0x00}, 12)
+	if err != nil || len(result) != 12 {
+		t.Errorf("decompress: %v %v", result, err)
+	}
+}
+
+func TestAdpcm(t *testing.T) {
+	test := func(data []byte, size, channelCount int, expected ...int16) {
+		var expectedData []byte
+		for _, sample := range expected {
+			expectedData = append(expectedData, byte(sample), byte(uint16(sample)>>8))
+		}
+
+		if result := decompressAdpcm(data, size, channelCount); !bytes.Equal(result, expectedData) {
+			t.Errorf("result: %v, expected: %v", result, expectedData)
+		}
+	}
+
+	test([]byte{0x00, 0x04, 0x10, 0x00, 0x01, 0x41, 0x03, 0x80, 0x00}, 12, 1, 16, 540, 16, 787, 787, 828)
+	test([]byte{0x00, 0x04, 0x10, 0x00, 0x01, 0x41, 0x03, 0x80, 0x00}, 4, 1, 16, 540)
+	test([]byte{0x00, 0x02, 0x00, 0x01, 0x00, 0xff, 0x02, 0x42, 0x81, 0x05}, 10, 2, 256, -256, 626, -626, 2069)
+	test([]byte{0x00, 0x00, 0x00, 0x7d, 0x3f}, 4, 1, 32000, 32767)
+	test([]byte{0x00, 0x00, 0x00, 0x83, 0x7f}, 4, 1, -32000, -32768)
+	test([]byte{0x00}, 4, 1)
+
+	result, err := decompress([]byte{compressionAdpcmStereo, 0x00, 0x02, 0x00, 0x01, 0x00, 0xff, 0x02, 0x42, 0x81, 0x05}, 10)
+	if err != nil || len(result) != 10 {
+		t.Errorf("decompress: %v %v", result, err)
+	}
+}
+
+func TestImplode(t *testing.T) {
+	data := testCompressionData()
+
+	result, err := explode(implode(data), len(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(result, data) {
+		t.Error("imploded data does not round trip")
+	}
+}
+
+func TestCompress(t *testing.T) {
+	data := testCompressionData()
+
+	for _, mask := range []byte{compressionZlib, compressionBzip2, compressionImplode} {
+		compressed, err := compress(data, mask)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := decompress(compressed, len(data))
+		if err != nil {
+			t.Fatalf("mask %#x: %v", mask, err)
+		}
+
+		if !bytes.Equal(result, data) {
+			t.Errorf("mask %#x: compressed data does not round trip", mask)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "test.mpq")
+		files = map[string][]byte{
+			"data\\global\\plain.txt":   []byte("plain"),
+			"data\\global\\empty.bin":   nil,
+			"data\\global\\zlib.bin":    testCompressionData(),
+			"data\\global\\bzip2.bin":   testCompressionData()[:5000],
+			"data\\global\\implode.bin": testCompressionData()[1:],
+		}
+		options = map[string]FileOptions{
+			"data\\global\\zlib.bin":    {Compression: CompressZlib, Encrypt: true},
+			"data\\global\\bzip2.bin":   {Compression: CompressBzip2},
+			"data\\global\\implode.bin": {Compression: CompressImplode, Encrypt: true, FixKey: true},
+		}
+	)
+
+	w, err := NewWriter(path, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range files {
+		if err := w.AddFile(name, data, options[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.AddFile("data\\global\\plain.txt", nil, FileOptions{}); err == nil {
+		t.Error("duplicate file was added")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	if paths := a.GetPaths(); len(paths) != len(files) {
+		t.Errorf("paths: %d, expected: %d", len(paths), len(files))
+	}
+
+	for name, data := range files {
+		f, err := a.OpenFile(sanitizePath(name))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := ioutil.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(result, data) {
+			t.Errorf("%s: contents do not round trip", name)
+		}
+	}
+}
+
+func TestFileSystem(t *testing.T) {
+	var (
+		path = filepath.Join(t.TempDir(), "test.mpq")
+		data = testCompressionData()
+	)
+
+	w, err := NewWriter(path, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddFile("Data\\Global\\Items.TXT", data, FileOptions{Compression: CompressZlib, Encrypt: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.AddFile("data\\plain.bin", data[:100], FileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.writeFile("data\\unnamed.bin", data[:200], FileOptions{Compression: CompressImplode}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	if err := fstest.TestFS(a, "data/global/items.txt", "data/plain.bin", "file00000002.xxx"); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := a.Glob("DATA/*/Items.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 1 || matches[0] != "data/global/items.txt" {
+		t.Errorf("glob matches: %v", matches)
+	}
+
+	info, err := a.Stat("data/global/items.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Size() != int64(len(data)) || info.IsDir() {
+		t.Errorf("unexpected file info: %s %d", info.Name(), info.Size())
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	var (
+		path  = filepath.Join(t.TempDir(), "test.mpq")
+		files = make(map[string][]byte)
+	)
+
+	w, err := NewWriter(path, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 16; i++ {
+		name := fmt.Sprintf("data\\file%02d.bin", i)
+		data := testCompressionData()[i*100:]
+		if err := w.AddFile(name, data, FileOptions{Compression: Compression(i % 4), Encrypt: i%2 == 0}); err != nil {
+			t.Fatal(err)
+		}
+
+		files[sanitizePath(name)] = data
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	shared, err := a.OpenFile(sanitizePath("data\\file00.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shared.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(seed int) {
+			defer wg.Done()
+
+			for name, data := range files {
+				f, err := a.OpenFile(name)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				result, err := ioutil.ReadAll(f)
+				f.Close()
+				if err != nil || !bytes.Equal(result, data) {
+					t.Errorf("%s: contents do not match (%v)", name, err)
+				}
+			}
+
+			data := files[sanitizePath("data\\file00.bin")]
+			for j := 0; j < 8; j++ {
+				var (
+					offset = (seed*7919 + j*104729) % len(data)
+					buff   = make([]byte, 5000)
+				)
+
+				count, err := shared.ReadAt(buff, int64(offset))
+				if err != nil && err != io.EOF {
+					t.Error(err)
+					return
+				}
+
+				if !bytes.Equal(buff[:count], data[offset:offset+count]) || count < len(buff) && offset+count != len(data) {
+					t.Errorf("read at %d: contents do not match", offset)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestConcurrentListFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.mpq")
+
+	w, err := NewWriter(path, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for i := 0; i < 16; i++ {
+		name := fmt.Sprintf("data\\file%02d.bin", i)
+		if err := w.writeFile(name, []byte(name), FileOptions{}); err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, name)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := NewFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(2)
+		go func(name string) {
+			defer wg.Done()
+
+			if err := a.AddListFile(strings.NewReader(name)); err != nil {
+				t.Error(err)
+			}
+		}(name)
+
+		go func(seed int) {
+			defer wg.Done()
+
+			for j := 0; j < 8; j++ {
+				a.Entries()
+				a.GetPaths()
+				a.Glob("data/*")
+
+				if f, err := a.OpenFile(names[(seed+j)%len(names)]); err == nil {
+					f.Close()
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if paths := a.GetPaths(); len(paths) != len(names) {
+		t.Errorf("paths: %d, expected: %d", len(paths), len(names))
+	}
+}
@@ -0,0 +1,250 @@
+//go:build stormlib
+// +build stormlib
+
+package mpq
+
+// #cgo windows CFLAGS: -D_MPQ_WINDOWS
+// #cgo windows LDFLAGS: -Lstormlib -lstorm -lwininet -lz -lbz2 -lstdc++
+// #cgo linux CFLAGS: -D_MPQ_LINUX
+// #cgo linux LDFLAGS: -L./stormlib/ -lstorm -lz -lbz2 -lstdc++
+// #ifdef _MPQ_WINDOWS
+// #include "native_windows.h"
+// #endif
+// #ifdef _MPQ_LINUX
+// #include "native_linux.h"
+// #endif
+import "C"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+type Archive struct {
+	handle    unsafe.Pointer
+	mutex     sync.Mutex
+	paths     map[string]string
+	unnamed   map[string]hashEntry
+	dirs      map[string][]string
+	pathMutex sync.RWMutex
+}
+
+func NewFromFile(path string) (*Archive, error) {
+	cs := C.CString(path)
+	defer C.free(unsafe.Pointer(cs))
+
+	a := new(Archive)
+	if result := C.SFileOpenArchive(cs, 0, 0, (*C.HANDLE)(&a.handle)); result == 0 {
+		return nil, fmt.Errorf("failed to open archive (%d)", getLastError())
+	}
+
+	if err := a.buildPathMap(); err != nil {
+		a.Close()
+		return nil, err
+	}
+
+	return a, nil
+}
+
+func (a *Archive) Close() error {
+	a.pathMutex.Lock()
+	defer a.pathMutex.Unlock()
+
+	a.lock()
+	defer a.unlock()
+
+	if result := C.SFileCloseArchive(C.HANDLE(a.handle)); result == 0 {
+		return fmt.Errorf("failed to close archive (%d)", getLastError())
+	}
+
+	a.handle = nil
+	a.paths = nil
+	a.unnamed = nil
+	a.dirs = nil
+	return nil
+}
+
+func (a *Archive) OpenFile(path string) (*File, error) {
+	a.pathMutex.RLock()
+	defer a.pathMutex.RUnlock()
+
+	return a.openFile(path)
+}
+
+func (a *Archive) openFile(path string) (*File, error) {
+	if pathInt, ok := a.paths[path]; ok {
+		path = pathInt
+	}
+
+	cs := C.CString(path)
+	defer C.free(unsafe.Pointer(cs))
+
+	a.lock()
+	defer a.unlock()
+
+	file := &File{archive: a}
+	if result := C.SFileOpenFileEx(C.HANDLE(a.handle), cs, 0, (*C.HANDLE)(&file.handle)); result == 0 {
+		return nil, fmt.Errorf("failed to open file (%d)", getLastError())
+	}
+
+	return file, nil
+}
+
+func (a *Archive) fileSize(path string) (int64, error) {
+	// storm does not expose block entries, so the file handle is needed for the size
+	file, err := a.openFile(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	return file.Size(), nil
+}
+
+func (a *Archive) lock() {
+	a.mutex.Lock()
+	runtime.LockOSThread()
+}
+
+func (a *Archive) unlock() {
+	runtime.UnlockOSThread()
+	a.mutex.Unlock()
+}
+
+type File struct {
+	handle  unsafe.Pointer
+	archive *Archive
+}
+
+func (f *File) Read(data []byte) (int, error) {
+	f.archive.lock()
+	defer f.archive.unlock()
+
+	return f.read(data)
+}
+
+func (f *File) ReadAt(data []byte, offset int64) (int, error) {
+	f.archive.lock()
+	defer f.archive.unlock()
+
+	position, err := f.seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	defer f.seek(position, io.SeekStart)
+
+	if _, err := f.seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	var count int
+	for count < len(data) {
+		bytesRead, err := f.read(data[count:])
+		count += bytesRead
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+func (f *File) read(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	var bytesRead int
+	if result := C.SFileReadFile(C.HANDLE(f.handle), unsafe.Pointer(&data[0]), C.ulong(len(data)), (*C.ulong)(unsafe.Pointer(&bytesRead)), nil); result == 0 {
+		lastError := getLastError()
+		if lastError == C.ERROR_HANDLE_EOF {
+			return bytesRead, io.EOF
+		}
+
+		return 0, fmt.Errorf("failed to read file (%d)", lastError)
+	}
+
+	return bytesRead, nil
+}
+
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	f.archive.lock()
+	defer f.archive.unlock()
+
+	return f.seek(offset, whence)
+}
+
+func (f *File) seek(offset int64, whence int) (int64, error) {
+	var method uint
+	switch whence {
+	case io.SeekStart:
+		method = C.FILE_BEGIN
+	case io.SeekCurrent:
+		method = C.FILE_CURRENT
+	case io.SeekEnd:
+		method = C.FILE_END
+	}
+
+	result := C.SFileSetFilePointer(C.HANDLE(f.handle), C.long(offset), nil, C.ulong(method))
+	if result == C.SFILE_INVALID_SIZE {
+		return 0, fmt.Errorf("failed to set file pointer (%d)", getLastError())
+	}
+
+	return int64(result), nil
+}
+
+func (f *File) Size() int64 {
+	f.archive.lock()
+	defer f.archive.unlock()
+
+	result := C.SFileGetFileSize(C.HANDLE(f.handle), nil)
+	if result == C.SFILE_INVALID_SIZE {
+		return 0
+	}
+
+	return int64(result)
+}
+
+func (f *File) Close() error {
+	f.archive.lock()
+	defer f.archive.unlock()
+
+	if result := C.SFileCloseFile(C.HANDLE(f.handle)); result == 0 {
+		return fmt.Errorf("failed to close file (%d)", getLastError())
+	}
+
+	f.handle = nil
+	return nil
+}
+
+func (a *Archive) hasFile(path string) bool {
+	cs := C.CString(path)
+	defer C.free(unsafe.Pointer(cs))
+
+	a.lock()
+	defer a.unlock()
+
+	return C.SFileHasFile(C.HANDLE(a.handle), cs) != 0
+}
+
+func (a *Archive) buildUnnamedMap() {
+}
+
+func (a *Archive) HashTableSize() int {
+	return 0
+}
+
+func (w *Writer) CopyFile(a *Archive, path string) error {
+	return errors.New("raw file copies are not supported by stormlib")
+}
+
+func (a *Archive) Verify() (*VerifyReport, error) {
+	return nil, errors.New("archive verification is not supported")
+}
+
+func getLastError() uint {
+	return uint(C.GetLastError())
+}
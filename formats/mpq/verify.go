@@ -0,0 +1,65 @@
+package mpq
+
+import "fmt"
+
+type VerifyIssueKind string
+
+const (
+	VerifyInvalidBlock VerifyIssueKind = "invalid-block"
+	VerifyCollision    VerifyIssueKind = "collision"
+	VerifyUnreachable  VerifyIssueKind = "unreachable"
+	VerifyUnresolved   VerifyIssueKind = "unresolved"
+	VerifyUnnamed      VerifyIssueKind = "unnamed"
+	VerifyCorrupt      VerifyIssueKind = "corrupt"
+	VerifySectorCrc    VerifyIssueKind = "sector-crc"
+	VerifyCrc32        VerifyIssueKind = "crc32"
+	VerifyMd5          VerifyIssueKind = "md5"
+)
+
+type VerifyIssue struct {
+	Kind    VerifyIssueKind `json:"kind"`
+	Block   int             `json:"block"`
+	Path    string          `json:"path,omitempty"`
+	Message string          `json:"message"`
+}
+
+func (i VerifyIssue) String() string {
+	var location string
+	switch {
+	case i.Block >= 0 && len(i.Path) > 0:
+		location = fmt.Sprintf("block %d (%s)", i.Block, i.Path)
+	case i.Block >= 0:
+		location = fmt.Sprintf("block %d", i.Block)
+	default:
+		location = i.Path
+	}
+
+	return fmt.Sprintf("%s: %s: %s", location, i.Kind, i.Message)
+}
+
+type VerifyReport struct {
+	HashEntries   int           `json:"hashEntries"`
+	Blocks        int           `json:"blocks"`
+	VerifiedFiles int           `json:"verifiedFiles"`
+	Attributes    bool          `json:"attributes"`
+	Issues        []VerifyIssue `json:"issues"`
+}
+
+func (r *VerifyReport) Ok() bool {
+	return len(r.Issues) == 0
+}
+
+func (r *VerifyReport) addIssue(kind VerifyIssueKind, block int, path, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, VerifyIssue{kind, block, path, fmt.Sprintf(format, args...)})
+}
+
+func sectorChecksum(data []byte) uint32 {
+	// storm seeds adler32 with zero rather than one
+	var a, b uint32
+	for _, value := range data {
+		a = (a + uint32(value)) % 65521
+		b = (b + a) % 65521
+	}
+
+	return b<<16 | a
+}
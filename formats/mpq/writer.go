@@ -0,0 +1,323 @@
+package mpq
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"strings"
+)
+
+type Compression int
+
+const (
+	CompressNone Compression = iota
+	CompressImplode
+	CompressZlib
+	CompressBzip2
+)
+
+const (
+	writerHeaderSize      = 32
+	writerSectorSizeShift = 3
+)
+
+type FileOptions struct {
+	Compression Compression
+	Encrypt     bool
+	FixKey      bool
+}
+
+type Writer struct {
+	file       *os.File
+	offset     uint32
+	hashTable  []hashEntry
+	blockTable []blockEntry
+	checksums  []writerChecksum
+	paths      []string
+	copySource *Archive
+}
+
+type writerChecksum struct {
+	crc32 uint32
+	md5   [md5.Size]byte
+}
+
+func NewWriter(path string, hashTableSize int) (*Writer, error) {
+	if hashTableSize <= 0 || hashTableSize&(hashTableSize-1) != 0 {
+		return nil, errors.New("hash table size must be a power of two")
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Write(make([]byte, writerHeaderSize)); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	w := &Writer{
+		file:      file,
+		offset:    writerHeaderSize,
+		hashTable: make([]hashEntry, hashTableSize),
+	}
+
+	for i := range w.hashTable {
+		w.hashTable[i] = hashEntry{
+			NameA:      hashEntryEmpty,
+			NameB:      hashEntryEmpty,
+			Locale:     0xffff,
+			Platform:   0xffff,
+			BlockIndex: hashEntryEmpty,
+		}
+	}
+
+	return w, nil
+}
+
+func (w *Writer) AddFile(path string, data []byte, options FileOptions) error {
+	if w.file == nil {
+		return errors.New("writer is not open")
+	}
+
+	path = strings.Replace(path, "/", "\\", -1)
+	if strings.HasPrefix(path, "(") {
+		return errors.New("reserved file name")
+	}
+
+	if err := w.writeFile(path, data, options); err != nil {
+		return err
+	}
+
+	w.paths = append(w.paths, path)
+	return nil
+}
+
+func (w *Writer) Close() error {
+	if w.file == nil {
+		return errors.New("writer is not open")
+	}
+
+	err := w.finalize()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+
+	w.file = nil
+	return err
+}
+
+func (w *Writer) finalize() error {
+	options := FileOptions{Compression: CompressImplode}
+
+	var listFile bytes.Buffer
+	for _, path := range w.paths {
+		listFile.WriteString(path)
+		listFile.WriteString("\r\n")
+	}
+
+	if err := w.writeFile("(listfile)", listFile.Bytes(), options); err != nil {
+		return err
+	}
+
+	var attributes bytes.Buffer
+	binary.Write(&attributes, binary.LittleEndian, uint32(attributesVersion))
+	binary.Write(&attributes, binary.LittleEndian, uint32(attributesCrc32|attributesMd5))
+	for _, checksum := range w.checksums {
+		binary.Write(&attributes, binary.LittleEndian, checksum.crc32)
+	}
+	binary.Write(&attributes, binary.LittleEndian, uint32(0))
+	for _, checksum := range w.checksums {
+		attributes.Write(checksum.md5[:])
+	}
+	attributes.Write(make([]byte, md5.Size))
+
+	if err := w.writeFile("(attributes)", attributes.Bytes(), options); err != nil {
+		return err
+	}
+
+	hashTablePos := w.offset
+	if err := w.writeTable("(hash table)", w.hashTable); err != nil {
+		return err
+	}
+
+	blockTablePos := w.offset
+	if err := w.writeTable("(block table)", w.blockTable); err != nil {
+		return err
+	}
+
+	header := archiveHeader{
+		Signature:       archiveSignature,
+		HeaderSize:      writerHeaderSize,
+		ArchiveSize:     w.offset,
+		SectorSizeShift: writerSectorSizeShift,
+		HashTablePos:    hashTablePos,
+		BlockTablePos:   blockTablePos,
+		HashTableSize:   uint32(len(w.hashTable)),
+		BlockTableSize:  uint32(len(w.blockTable)),
+	}
+
+	if _, err := w.file.Seek(0, os.SEEK_SET); err != nil {
+		return err
+	}
+
+	return binary.Write(w.file, binary.LittleEndian, header)
+}
+
+func (w *Writer) writeTable(name string, table interface{}) error {
+	var buff bytes.Buffer
+	if err := binary.Write(&buff, binary.LittleEndian, table); err != nil {
+		return err
+	}
+
+	data := buff.Bytes()
+	encryptBytes(data, hashString(name, hashTypeFileKey))
+
+	return w.write(data)
+}
+
+func (w *Writer) writeFile(path string, data []byte, options FileOptions) error {
+	hashIndex, err := w.findFreeHashEntry(path)
+	if err != nil {
+		return err
+	}
+
+	block := blockEntry{
+		FilePos:  w.offset,
+		FileSize: uint32(len(data)),
+		Flags:    fileExists,
+	}
+
+	var mask byte
+	switch options.Compression {
+	case CompressNone:
+	case CompressImplode:
+		mask = compressionImplode
+	case CompressZlib:
+		mask = compressionZlib
+	case CompressBzip2:
+		mask = compressionBzip2
+	default:
+		return errors.New("unsupported compression type")
+	}
+
+	if mask != 0 && len(data) > 0 {
+		block.Flags |= fileCompress
+	}
+
+	if options.Encrypt {
+		block.Flags |= fileEncrypted
+		if options.FixKey {
+			block.Flags |= fileFixKey
+		}
+	}
+
+	var (
+		sectorSize = 0x200 << writerSectorSizeShift
+		sectors    [][]byte
+	)
+
+	for offset := 0; offset < len(data); offset += sectorSize {
+		end := offset + sectorSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		sector := data[offset:end]
+		if block.Flags&fileCompress != 0 {
+			compressed, err := compress(sector, mask)
+			if err != nil {
+				return err
+			}
+
+			if len(compressed) < len(sector) {
+				sector = compressed
+			}
+		}
+
+		sectors = append(sectors, append([]byte(nil), sector...))
+	}
+
+	var key uint32
+	if block.Flags&fileEncrypted != 0 {
+		key = fileKey(path, block)
+	}
+
+	var output bytes.Buffer
+	if block.Flags&fileCompress != 0 {
+		offsets := make([]byte, (len(sectors)+1)*4)
+		position := uint32(len(offsets))
+		for i, sector := range sectors {
+			binary.LittleEndian.PutUint32(offsets[i*4:], position)
+			position += uint32(len(sector))
+		}
+		binary.LittleEndian.PutUint32(offsets[len(sectors)*4:], position)
+
+		if block.Flags&fileEncrypted != 0 {
+			encryptBytes(offsets, key-1)
+		}
+
+		output.Write(offsets)
+	}
+
+	for i, sector := range sectors {
+		if block.Flags&fileEncrypted != 0 {
+			encryptBytes(sector, key+uint32(i))
+		}
+
+		output.Write(sector)
+	}
+
+	block.CompressedSize = uint32(output.Len())
+	if err := w.write(output.Bytes()); err != nil {
+		return err
+	}
+
+	w.hashTable[hashIndex] = hashEntry{
+		NameA:      hashString(path, hashTypeNameA),
+		NameB:      hashString(path, hashTypeNameB),
+		BlockIndex: uint32(len(w.blockTable)),
+	}
+
+	w.blockTable = append(w.blockTable, block)
+	w.checksums = append(w.checksums, writerChecksum{crc32.ChecksumIEEE(data), md5.Sum(data)})
+
+	return nil
+}
+
+func (w *Writer) findFreeHashEntry(path string) (int, error) {
+	var (
+		mask  = uint32(len(w.hashTable) - 1)
+		start = hashString(path, hashTypeOffset) & mask
+		nameA = hashString(path, hashTypeNameA)
+		nameB = hashString(path, hashTypeNameB)
+	)
+
+	for i := start; ; {
+		hash := w.hashTable[i]
+		if hash.BlockIndex == hashEntryEmpty {
+			return int(i), nil
+		}
+
+		if hash.NameA == nameA && hash.NameB == nameB {
+			return 0, errors.New("file already exists in archive")
+		}
+
+		if i = (i + 1) & mask; i == start {
+			return 0, errors.New("archive hash table is full")
+		}
+	}
+}
+
+func (w *Writer) write(data []byte) error {
+	if _, err := w.file.Write(data); err != nil {
+		return err
+	}
+
+	w.offset += uint32(len(data))
+	return nil
+}
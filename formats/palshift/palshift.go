@@ -0,0 +1,39 @@
+package palshift
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+)
+
+type Colormap struct {
+	Transforms []dat.Transform
+}
+
+func NewFromReader(reader io.Reader) (*Colormap, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 || len(data)%len(dat.Transform{}) != 0 {
+		return nil, errors.New("invalid colormap size")
+	}
+
+	colormap := &Colormap{Transforms: make([]dat.Transform, len(data)/len(dat.Transform{}))}
+	for i := range colormap.Transforms {
+		copy(colormap.Transforms[i][:], data[i*len(dat.Transform{}):])
+	}
+
+	return colormap, nil
+}
+
+func (c *Colormap) Remap(index int, indices []byte) ([]byte, error) {
+	if index < 0 || index >= len(c.Transforms) {
+		return nil, errors.New("colormap index out of range")
+	}
+
+	return c.Transforms[index].Apply(indices), nil
+}
@@ -0,0 +1,39 @@
+package palshift
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRemap(t *testing.T) {
+	data := make([]byte, 256*3)
+	for i := range data {
+		data[i] = byte(i/256 + i%256)
+	}
+
+	colormap, err := NewFromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(colormap.Transforms) != 3 {
+		t.Fatalf("transforms: %d, expected: 3", len(colormap.Transforms))
+	}
+
+	result, err := colormap.Remap(2, []byte{0, 1, 255})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []byte{2, 3, 1}; !bytes.Equal(result, expected) {
+		t.Errorf("remapped: %v, expected: %v", result, expected)
+	}
+
+	if _, err := colormap.Remap(3, nil); err == nil {
+		t.Error("out of range colormap index was accepted")
+	}
+
+	if _, err := NewFromReader(bytes.NewReader(data[:300])); err == nil {
+		t.Error("truncated colormap was accepted")
+	}
+}
@@ -0,0 +1,108 @@
+package pl2
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+	"github.com/FooSoft/lazarus/math"
+)
+
+type color struct {
+	R byte
+	G byte
+	B byte
+	_ byte
+}
+
+type textColor struct {
+	R byte
+	G byte
+	B byte
+}
+
+type fileData struct {
+	Base              [256]color
+	LightLevels       [32]dat.Transform
+	InventoryColors   [16]dat.Transform
+	Selection         dat.Transform
+	AlphaBlend        [3][256]dat.Transform
+	AdditiveBlend     [256]dat.Transform
+	MultiplyBlend     [256]dat.Transform
+	HueVariations     [111]dat.Transform
+	RedTones          dat.Transform
+	GreenTones        dat.Transform
+	BlueTones         dat.Transform
+	UnknownVariations [14]dat.Transform
+	MaxComponentBlend [256]dat.Transform
+	DarkenedShift     dat.Transform
+	TextColors        [13]textColor
+	TextColorShifts   [13]dat.Transform
+}
+
+type Tables struct {
+	Base              dat.Palette
+	LightLevels       [32]dat.Transform
+	InventoryColors   [16]dat.Transform
+	Selection         dat.Transform
+	AlphaBlend        [3]BlendTable
+	AdditiveBlend     BlendTable
+	MultiplyBlend     BlendTable
+	HueVariations     [111]dat.Transform
+	RedTones          dat.Transform
+	GreenTones        dat.Transform
+	BlueTones         dat.Transform
+	UnknownVariations [14]dat.Transform
+	MaxComponentBlend BlendTable
+	DarkenedShift     dat.Transform
+	TextColors        [13]math.Color3b
+	TextColorShifts   [13]dat.Transform
+}
+
+type BlendTable [256]dat.Transform
+
+func NewFromReader(reader io.Reader) (*Tables, error) {
+	data := new(fileData)
+	if err := binary.Read(reader, binary.LittleEndian, data); err != nil {
+		return nil, err
+	}
+
+	tables := &Tables{
+		LightLevels:       data.LightLevels,
+		InventoryColors:   data.InventoryColors,
+		Selection:         data.Selection,
+		HueVariations:     data.HueVariations,
+		RedTones:          data.RedTones,
+		GreenTones:        data.GreenTones,
+		BlueTones:         data.BlueTones,
+		UnknownVariations: data.UnknownVariations,
+		DarkenedShift:     data.DarkenedShift,
+		TextColorShifts:   data.TextColorShifts,
+	}
+
+	for i, color := range data.Base {
+		tables.Base.Colors[i] = math.Color3b{R: color.R, G: color.G, B: color.B}
+	}
+
+	for i := range data.AlphaBlend {
+		tables.AlphaBlend[i] = BlendTable(data.AlphaBlend[i])
+	}
+
+	tables.AdditiveBlend = BlendTable(data.AdditiveBlend)
+	tables.MultiplyBlend = BlendTable(data.MultiplyBlend)
+	tables.MaxComponentBlend = BlendTable(data.MaxComponentBlend)
+
+	for i, color := range data.TextColors {
+		tables.TextColors[i] = math.Color3b{R: color.R, G: color.G, B: color.B}
+	}
+
+	return tables, nil
+}
+
+func (t *BlendTable) Apply(src, dst []byte) {
+	for i, index := range src {
+		if index != 0 {
+			dst[i] = t[index][dst[i]]
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package pl2
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/FooSoft/lazarus/math"
+)
+
+const testFileSize = 443175
+
+func TestNewFromReader(t *testing.T) {
+	if size := binary.Size(fileData{}); size != testFileSize {
+		t.Fatalf("file size: %d, expected: %d", size, testFileSize)
+	}
+
+	data := make([]byte, testFileSize)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	tables, err := NewFromReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if color := tables.Base.Colors[1]; color != (math.Color3b{R: data[4], G: data[5], B: data[6]}) {
+		t.Errorf("base color: %+v", color)
+	}
+
+	offset := 1024 + 256*(32+16+1)
+	if value := tables.AlphaBlend[1][2][3]; value != data[offset+256*256+2*256+3] {
+		t.Errorf("alpha blend value: %d", value)
+	}
+
+	offset = testFileSize - 13*256 - 13*3
+	if color := tables.TextColors[12]; color != (math.Color3b{R: data[offset+36], G: data[offset+37], B: data[offset+38]}) {
+		t.Errorf("text color: %+v", color)
+	}
+
+	if value := tables.TextColorShifts[12][255]; value != data[testFileSize-1] {
+		t.Errorf("text color shift value: %d", value)
+	}
+
+	if _, err := NewFromReader(bytes.NewReader(data[:testFileSize-1])); err == nil {
+		t.Error("truncated file was accepted")
+	}
+}
+
+func TestBlend(t *testing.T) {
+	var table BlendTable
+	for i := range table {
+		for j := range table[i] {
+			table[i][j] = byte(i ^ j)
+		}
+	}
+
+	dst := []byte{1, 2, 3, 4}
+	table.Apply([]byte{0, 1, 2, 0}, dst)
+
+	if expected := []byte{1, 3, 1, 4}; !bytes.Equal(dst, expected) {
+		t.Errorf("blended: %v, expected: %v", dst, expected)
+	}
+}
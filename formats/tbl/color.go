@@ -0,0 +1,69 @@
+package tbl
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const colorPrefix = "ÿc"
+
+const (
+	ColorWhite = iota
+	ColorRed
+	ColorGreen
+	ColorBlue
+	ColorGold
+	ColorGray
+	ColorBlack
+	ColorTan
+	ColorOrange
+	ColorYellow
+	ColorDarkGreen
+	ColorPurple
+)
+
+type Segment struct {
+	Color int
+	Text  string
+}
+
+func Colorize(text string, color int) []Segment {
+	var segments []Segment
+	for {
+		index := strings.Index(text, colorPrefix)
+		if index < 0 {
+			break
+		}
+
+		if index > 0 {
+			segments = append(segments, Segment{color, text[:index]})
+		}
+
+		text = text[index+len(colorPrefix):]
+		if len(text) == 0 {
+			break
+		}
+
+		char, size := utf8.DecodeRuneInString(text)
+		if code := int(char - '0'); code >= ColorWhite && code <= ColorPurple {
+			color = code
+		}
+
+		text = text[size:]
+	}
+
+	if len(text) > 0 {
+		segments = append(segments, Segment{color, text})
+	}
+
+	return segments
+}
+
+func StripColors(text string) string {
+	var result strings.Builder
+	for _, segment := range Colorize(text, ColorWhite) {
+		result.WriteString(segment.Text)
+	}
+
+	return result.String()
+}
@@ -0,0 +1,101 @@
+package tbl
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+)
+
+const (
+	languageDir        = "data/local/lng"
+	patchIndexBase     = 10000
+	expansionIndexBase = 20000
+)
+
+type Strings struct {
+	Base      *Table
+	Expansion *Table
+	Patch     *Table
+}
+
+func Load(fsys fs.FS, language string) (*Strings, error) {
+	return LoadDir(fsys, path.Join(languageDir, language))
+}
+
+func LoadDir(fsys fs.FS, dir string) (*Strings, error) {
+	var (
+		result = new(Strings)
+		err    error
+	)
+
+	if result.Base, err = loadTable(fsys, path.Join(dir, "string.tbl")); err != nil {
+		return nil, err
+	}
+
+	if result.Expansion, err = loadTable(fsys, path.Join(dir, "expansionstring.tbl")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	if result.Patch, err = loadTable(fsys, path.Join(dir, "patchstring.tbl")); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func Languages(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, languageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var languages []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			languages = append(languages, entry.Name())
+		}
+	}
+
+	return languages, nil
+}
+
+func (s *Strings) Lookup(key string) string {
+	for _, table := range []*Table{s.Patch, s.Expansion, s.Base} {
+		if table == nil {
+			continue
+		}
+
+		if text, ok := table.Lookup(key); ok {
+			return text
+		}
+	}
+
+	return key
+}
+
+func (s *Strings) LookupIndex(index int) string {
+	table := s.Base
+	switch {
+	case index >= expansionIndexBase:
+		table, index = s.Expansion, index-expansionIndexBase
+	case index >= patchIndexBase:
+		table, index = s.Patch, index-patchIndexBase
+	}
+
+	if table == nil {
+		return ""
+	}
+
+	text, _ := table.LookupIndex(index)
+	return text
+}
+
+func loadTable(fsys fs.FS, name string) (*Table, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return NewFromReader(file)
+}
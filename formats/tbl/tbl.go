@@ -0,0 +1,132 @@
+package tbl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+type fileHeader struct {
+	Crc           uint16
+	ElementCount  uint16
+	HashTableSize uint32
+	Version       uint8
+	DataStart     uint32
+	HashMaxTries  uint32
+	FileSize      uint32
+}
+
+type hashEntry struct {
+	Used         uint8
+	Index        uint16
+	Hash         uint32
+	KeyOffset    uint32
+	StringOffset uint32
+	StringLength uint16
+}
+
+type Entry struct {
+	Key  string
+	Text string
+}
+
+type Table struct {
+	Entries []Entry
+	keys    map[string]int
+}
+
+func NewFromReader(reader io.Reader) (*Table, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	buff := bytes.NewReader(data)
+
+	var fileHead fileHeader
+	if err := binary.Read(buff, binary.LittleEndian, &fileHead); err != nil {
+		return nil, err
+	}
+
+	indices := make([]uint16, fileHead.ElementCount)
+	if err := binary.Read(buff, binary.LittleEndian, indices); err != nil {
+		return nil, err
+	}
+
+	hashEntries := make([]hashEntry, fileHead.HashTableSize)
+	if err := binary.Read(buff, binary.LittleEndian, hashEntries); err != nil {
+		return nil, err
+	}
+
+	table := &Table{
+		Entries: make([]Entry, len(indices)),
+		keys:    make(map[string]int),
+	}
+
+	for i, index := range indices {
+		if int(index) >= len(hashEntries) {
+			return nil, errors.New("invalid hash table index")
+		}
+
+		hash := hashEntries[index]
+		if hash.Used == 0 {
+			continue
+		}
+
+		key, err := readString(data, hash.KeyOffset)
+		if err != nil {
+			return nil, err
+		}
+
+		text, err := readString(data, hash.StringOffset)
+		if err != nil {
+			return nil, err
+		}
+
+		table.Entries[i] = Entry{key, text}
+		if _, ok := table.keys[key]; !ok {
+			table.keys[key] = i
+		}
+	}
+
+	return table, nil
+}
+
+func (t *Table) Lookup(key string) (string, bool) {
+	index, ok := t.keys[key]
+	if !ok {
+		return "", false
+	}
+
+	return t.Entries[index].Text, true
+}
+
+func (t *Table) LookupIndex(index int) (string, bool) {
+	if index < 0 || index >= len(t.Entries) {
+		return "", false
+	}
+
+	return t.Entries[index].Text, true
+}
+
+func readString(data []byte, offset uint32) (string, error) {
+	if int64(offset) >= int64(len(data)) {
+		return "", errors.New("invalid string offset")
+	}
+
+	data = data[offset:]
+	if index := bytes.IndexByte(data, 0); index >= 0 {
+		data = data[:index]
+	}
+
+	// strings are stored in the windows-1252 code page, which matches latin-1
+	// for the characters used by color codes and most localized text
+	runes := make([]rune, len(data))
+	for i, char := range data {
+		runes[i] = rune(char)
+	}
+
+	return string(runes), nil
+}
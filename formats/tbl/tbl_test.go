@@ -0,0 +1,145 @@
+package tbl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func buildTable(entries ...Entry) []byte {
+	var (
+		hashTableSize = len(entries) + 2
+		dataStart     = binary.Size(fileHeader{}) + len(entries)*2 + hashTableSize*binary.Size(hashEntry{})
+		indices       = make([]uint16, len(entries))
+		hashEntries   = make([]hashEntry, hashTableSize)
+		data          bytes.Buffer
+	)
+
+	for i, entry := range entries {
+		index := (i*3 + 1) % hashTableSize
+		indices[i] = uint16(index)
+
+		keyOffset := dataStart + data.Len()
+		data.WriteString(entry.Key + "\x00")
+		stringOffset := dataStart + data.Len()
+		data.WriteString(entry.Text + "\x00")
+
+		hashEntries[index] = hashEntry{
+			Used:         1,
+			Index:        uint16(i),
+			KeyOffset:    uint32(keyOffset),
+			StringOffset: uint32(stringOffset),
+			StringLength: uint16(len(entry.Text) + 1),
+		}
+	}
+
+	var buff bytes.Buffer
+	binary.Write(&buff, binary.LittleEndian, fileHeader{
+		ElementCount:  uint16(len(entries)),
+		HashTableSize: uint32(hashTableSize),
+		Version:       1,
+		DataStart:     uint32(dataStart),
+		HashMaxTries:  1,
+		FileSize:      uint32(dataStart + data.Len()),
+	})
+	binary.Write(&buff, binary.LittleEndian, indices)
+	binary.Write(&buff, binary.LittleEndian, hashEntries)
+	buff.Write(data.Bytes())
+
+	return buff.Bytes()
+}
+
+func TestNewFromReader(t *testing.T) {
+	entries := []Entry{{"WarpDist", "Teleport"}, {"Hello", "\xffc1Red\xffc0 text"}, {"WarpDist", "Duplicate"}}
+	table, err := NewFromReader(bytes.NewReader(buildTable(entries...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(table.Entries) != 3 {
+		t.Fatalf("entry count: %d", len(table.Entries))
+	}
+
+	if text, ok := table.Lookup("Hello"); !ok || text != "ÿc1Redÿc0 text" {
+		t.Errorf("lookup: %q", text)
+	}
+
+	if text, ok := table.Lookup("WarpDist"); !ok || text != "Teleport" {
+		t.Errorf("duplicate lookup: %q", text)
+	}
+
+	if text, ok := table.LookupIndex(2); !ok || text != "Duplicate" {
+		t.Errorf("index lookup: %q", text)
+	}
+
+	if _, ok := table.Lookup("Missing"); ok {
+		t.Error("missing key was found")
+	}
+
+	data := buildTable(entries...)
+	if _, err := NewFromReader(bytes.NewReader(data[:30])); err == nil {
+		t.Error("truncated table was accepted")
+	}
+}
+
+func TestStrings(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/local/lng/eng/string.tbl":          {Data: buildTable(Entry{"a", "base a"}, Entry{"b", "base b"}, Entry{"c", "base c"})},
+		"data/local/lng/eng/expansionstring.tbl": {Data: buildTable(Entry{"b", "expansion b"}, Entry{"c", "expansion c"})},
+		"data/local/lng/eng/patchstring.tbl":     {Data: buildTable(Entry{"c", "patch c"})},
+		"data/local/lng/deu/string.tbl":          {Data: buildTable(Entry{"a", "basis a"})},
+	}
+
+	strings, err := Load(fsys, "eng")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]string{"a": "base a", "b": "expansion b", "c": "patch c", "d": "d"} {
+		if text := strings.Lookup(key); text != expected {
+			t.Errorf("lookup %s: %q, expected: %q", key, text, expected)
+		}
+	}
+
+	for index, expected := range map[int]string{1: "base b", 10000: "patch c", 20001: "expansion c", 20002: ""} {
+		if text := strings.LookupIndex(index); text != expected {
+			t.Errorf("lookup index %d: %q, expected: %q", index, text, expected)
+		}
+	}
+
+	strings, err = Load(fsys, "deu")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if text := strings.Lookup("a"); text != "basis a" || strings.Expansion != nil || strings.Patch != nil {
+		t.Errorf("base only lookup: %q", text)
+	}
+
+	if _, err := Load(fsys, "fra"); err == nil {
+		t.Error("missing language was accepted")
+	}
+
+	languages, err := Languages(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(languages, []string{"deu", "eng"}) {
+		t.Errorf("languages: %v", languages)
+	}
+}
+
+func TestColorize(t *testing.T) {
+	segments := Colorize("Plainÿc4Goldÿc;Purpleÿc", ColorWhite)
+	expected := []Segment{{ColorWhite, "Plain"}, {ColorGold, "Gold"}, {ColorPurple, "Purple"}}
+	if !reflect.DeepEqual(segments, expected) {
+		t.Errorf("segments: %+v, expected: %+v", segments, expected)
+	}
+
+	if text := StripColors("ÿc1Redÿc0 text"); text != "Red text" {
+		t.Errorf("stripped text: %q", text)
+	}
+}
@@ -0,0 +1,189 @@
+package txt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strconv"
+)
+
+type BinFieldType int
+
+const (
+	BinUint8 BinFieldType = iota
+	BinUint16
+	BinUint32
+	BinInt8
+	BinInt16
+	BinInt32
+	BinString
+)
+
+type BinField struct {
+	Column string
+	Offset int
+	Type   BinFieldType
+	Size   int
+}
+
+type BinLayout struct {
+	RecordSize int
+	Fields     []BinField
+}
+
+var binItemsLayout = &BinLayout{
+	RecordSize: 0x1a8,
+	Fields: []BinField{
+		{"flippyfile", 0x00, BinString, 32},
+		{"invfile", 0x20, BinString, 32},
+		{"uniqueinvfile", 0x40, BinString, 32},
+		{"setinvfile", 0x60, BinString, 32},
+		{"code", 0x80, BinString, 4},
+		{"normcode", 0x84, BinString, 4},
+		{"ubercode", 0x88, BinString, 4},
+		{"ultracode", 0x8c, BinString, 4},
+		{"alternategfx", 0x90, BinString, 4},
+	},
+}
+
+var BinLayouts = map[string]*BinLayout{
+	"armor": binItemsLayout,
+	"itemstatcost": {
+		RecordSize: 0x144,
+		Fields: []BinField{
+			{"ID", 0x00, BinUint32, 0},
+			{"Send Bits", 0x08, BinUint8, 0},
+			{"Send Param Bits", 0x09, BinUint8, 0},
+			{"CSvBits", 0x0a, BinUint8, 0},
+			{"CSvParam", 0x0b, BinUint8, 0},
+			{"Divide", 0x0c, BinUint32, 0},
+			{"Multiply", 0x10, BinUint32, 0},
+			{"Add", 0x14, BinUint32, 0},
+			{"ValShift", 0x18, BinUint8, 0},
+			{"Save Bits", 0x19, BinUint8, 0},
+			{"1.09-Save Bits", 0x1a, BinUint8, 0},
+			{"Save Add", 0x1c, BinInt32, 0},
+			{"1.09-Save Add", 0x20, BinInt32, 0},
+			{"Save Param Bits", 0x24, BinUint32, 0},
+			{"MinAccr", 0x2c, BinUint32, 0},
+			{"Encode", 0x30, BinUint8, 0},
+			{"descpriority", 0x34, BinUint16, 0},
+			{"descfunc", 0x36, BinUint8, 0},
+			{"descval", 0x37, BinUint8, 0},
+		},
+	},
+	"levels": {
+		RecordSize: 0x220,
+		Fields: []BinField{
+			{"Id", 0x00, BinUint16, 0},
+			{"Pal", 0x02, BinUint8, 0},
+			{"Act", 0x03, BinUint8, 0},
+			{"Teleport", 0x04, BinUint8, 0},
+			{"Rain", 0x05, BinUint8, 0},
+			{"Mud", 0x06, BinUint8, 0},
+			{"NoPer", 0x07, BinUint8, 0},
+			{"IsInside", 0x08, BinUint8, 0},
+			{"DrawEdges", 0x09, BinUint8, 0},
+			{"WarpDist", 0x0c, BinUint32, 0},
+			{"MonLvl1", 0x10, BinUint16, 0},
+			{"MonLvl2", 0x12, BinUint16, 0},
+			{"MonLvl3", 0x14, BinUint16, 0},
+			{"MonLvl1Ex", 0x16, BinUint16, 0},
+			{"MonLvl2Ex", 0x18, BinUint16, 0},
+			{"MonLvl3Ex", 0x1a, BinUint16, 0},
+			{"MonDen", 0x1c, BinUint32, 0},
+			{"MonDen(N)", 0x20, BinUint32, 0},
+			{"MonDen(H)", 0x24, BinUint32, 0},
+		},
+	},
+	"misc": binItemsLayout,
+	"monstats": {
+		RecordSize: 0x1a8,
+		Fields: []BinField{
+			{"hcIdx", 0x00, BinUint16, 0},
+			{"Code", 0x10, BinString, 4},
+		},
+	},
+	"skills": {
+		RecordSize: 0x23c,
+		Fields: []BinField{
+			{"Id", 0x00, BinInt16, 0},
+		},
+	},
+	"weapons": binItemsLayout,
+}
+
+func NewFromBinReader(reader io.Reader, layout *BinLayout) (*Table, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 4 {
+		return nil, errors.New("table has no record count")
+	}
+
+	recordCount := int(binary.LittleEndian.Uint32(data))
+	if data = data[4:]; recordCount < 0 || len(data)/layout.RecordSize < recordCount {
+		return nil, errors.New("table is truncated")
+	}
+
+	table := new(Table)
+	for _, field := range layout.Fields {
+		if field.Offset+field.size() > layout.RecordSize {
+			return nil, errors.New("field does not fit in record")
+		}
+
+		table.Columns = append(table.Columns, field.Column)
+	}
+
+	for i := 0; i < recordCount; i++ {
+		record := data[i*layout.RecordSize : (i+1)*layout.RecordSize]
+		row := make([]string, len(layout.Fields))
+		for j, field := range layout.Fields {
+			row[j] = field.decode(record[field.Offset : field.Offset+field.size()])
+		}
+
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table, nil
+}
+
+func (f BinField) size() int {
+	switch f.Type {
+	case BinUint8, BinInt8:
+		return 1
+	case BinUint16, BinInt16:
+		return 2
+	case BinUint32, BinInt32:
+		return 4
+	default:
+		return f.Size
+	}
+}
+
+func (f BinField) decode(data []byte) string {
+	switch f.Type {
+	case BinUint8:
+		return strconv.FormatUint(uint64(data[0]), 10)
+	case BinUint16:
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint16(data)), 10)
+	case BinUint32:
+		return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data)), 10)
+	case BinInt8:
+		return strconv.FormatInt(int64(int8(data[0])), 10)
+	case BinInt16:
+		return strconv.FormatInt(int64(int16(binary.LittleEndian.Uint16(data))), 10)
+	case BinInt32:
+		return strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(data))), 10)
+	default:
+		if index := bytes.IndexByte(data, 0); index >= 0 {
+			data = data[:index]
+		}
+
+		return string(bytes.TrimRight(data, " "))
+	}
+}
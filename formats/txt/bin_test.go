@@ -0,0 +1,172 @@
+package txt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBinItemStatCost(t *testing.T) {
+	record := make([]byte, 0x144)
+	copy(record, []byte{
+		0x07, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1f, 0x00, 0x15, 0x00, 0x00, 0x04, 0x00, 0x00,
+		0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x08, 0x09, 0x08, 0x00, 0x20, 0x00, 0x00, 0x00,
+		0x20, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x36, 0x02, 0x01, 0x01,
+	})
+
+	data := append([]byte{0x01, 0x00, 0x00, 0x00}, record...)
+	table, err := NewFromBinReader(bytes.NewReader(data), BinLayouts["itemstatcost"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"ID":              "7",
+		"Send Bits":       "31",
+		"Send Param Bits": "0",
+		"CSvBits":         "21",
+		"CSvParam":        "0",
+		"Divide":          "1024",
+		"Multiply":        "256",
+		"Add":             "0",
+		"ValShift":        "8",
+		"Save Bits":       "9",
+		"1.09-Save Bits":  "8",
+		"Save Add":        "32",
+		"1.09-Save Add":   "32",
+		"Save Param Bits": "0",
+		"MinAccr":         "0",
+		"Encode":          "0",
+		"descpriority":    "566",
+		"descfunc":        "1",
+		"descval":         "1",
+	}
+
+	if len(table.Rows) != 1 {
+		t.Fatalf("row count: %d", len(table.Rows))
+	}
+
+	for column, value := range expected {
+		if index := table.Column(column); index < 0 || table.Rows[0][index] != value {
+			t.Errorf("column %s: %d, expected: %q", column, index, value)
+		}
+	}
+}
+
+func TestBinItems(t *testing.T) {
+	record := make([]byte, 0x1a8)
+	copy(record[0x00:], "flpcap")
+	copy(record[0x20:], "invcap")
+	copy(record[0x80:], []byte{'c', 'a', 'p', ' ', 'c', 'a', 'p', ' ', 'x', 'a', 'p', ' ', 'u', 'a', 'p', ' ', 'c', 'a', 'p', 0x00})
+
+	data := append([]byte{0x01, 0x00, 0x00, 0x00}, record...)
+	table, err := NewFromBinReader(bytes.NewReader(data), BinLayouts["armor"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"flpcap", "invcap", "", "", "cap", "cap", "xap", "uap", "cap"}
+	if len(table.Rows) != 1 || !reflect.DeepEqual(table.Rows[0], expected) {
+		t.Errorf("rows: %q, expected: %q", table.Rows, expected)
+	}
+}
+
+func TestBinMissingColumns(t *testing.T) {
+	type monsterRecord struct {
+		Id    int    `txt:"hcIdx"`
+		Code  string `txt:"Code"`
+		Level int    `txt:"Level"`
+	}
+
+	record := make([]byte, 0x1a8)
+	copy(record, []byte{0x05, 0x01})
+	copy(record[0x10:], "fa1")
+
+	table, err := NewFromBinReader(bytes.NewReader(append([]byte{0x01, 0x00, 0x00, 0x00}, record...)), BinLayouts["monstats"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []monsterRecord
+	if err := table.Decode(&records); err == nil {
+		t.Error("missing binary column was accepted")
+	}
+
+	textTable, err := NewFromReader(strings.NewReader("hcIdx\tCode\r\n261\tfa1\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := textTable.Decode(&records); err == nil {
+		t.Error("missing text column was accepted")
+	}
+
+	type mappedRecord struct {
+		Id    int    `txt:"hcIdx"`
+		Code  string `txt:"Code"`
+		Level int    `txt:"-"`
+	}
+
+	var mappedRecords []mappedRecord
+	if err := table.Decode(&mappedRecords); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []mappedRecord{{Id: 261, Code: "fa1"}}; !reflect.DeepEqual(mappedRecords, expected) {
+		t.Errorf("records: %+v, expected: %+v", mappedRecords, expected)
+	}
+}
+
+func TestBinDecode(t *testing.T) {
+	type levelRecord struct {
+		Id       int  `txt:"Id"`
+		Act      int  `txt:"Act"`
+		IsInside bool `txt:"IsInside"`
+		MonLvl3  int  `txt:"MonLvl3"`
+	}
+
+	const levels = "Name\tId\tPal\tAct\tIsInside\tMonLvl1\tMonLvl2\tMonLvl3\r\n" +
+		"Rogue Encampment\t1\t0\t0\t0\t1\t36\t67\r\n" +
+		"Expansion\r\n" +
+		"Harrogath\t109\t4\t4\t0\t1\t50\t75\r\n"
+
+	layout := BinLayouts["levels"]
+	data := make([]byte, 4+2*layout.RecordSize)
+	binary.LittleEndian.PutUint32(data, 2)
+	for i, record := range [][]uint16{{1, 0, 0, 67}, {109, 4, 0, 75}} {
+		recordData := data[4+i*layout.RecordSize:]
+		binary.LittleEndian.PutUint16(recordData, record[0])
+		recordData[0x03] = byte(record[1])
+		recordData[0x08] = byte(record[2])
+		binary.LittleEndian.PutUint16(recordData[0x14:], record[3])
+	}
+
+	txtTable, err := NewFromReader(strings.NewReader(levels))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binTable, err := NewFromBinReader(bytes.NewReader(data), layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var txtRecords, binRecords []levelRecord
+	if err := txtTable.Decode(&txtRecords); err != nil {
+		t.Fatal(err)
+	}
+	if err := binTable.Decode(&binRecords); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(txtRecords, binRecords) || len(binRecords) != 2 || binRecords[1].Act != 4 {
+		t.Errorf("binary records: %+v, text records: %+v", binRecords, txtRecords)
+	}
+
+	if _, err := NewFromBinReader(bytes.NewReader(data[:len(data)-1]), layout); err == nil {
+		t.Error("truncated table was accepted")
+	}
+}
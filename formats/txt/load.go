@@ -0,0 +1,42 @@
+package txt
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+func Open(fsys fs.FS, name string) (*Table, error) {
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ext := path.Ext(name)
+	if !strings.EqualFold(ext, ".bin") {
+		return NewFromReader(file)
+	}
+
+	layoutName := strings.ToLower(strings.TrimSuffix(path.Base(name), ext))
+	layout, ok := BinLayouts[layoutName]
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported binary table", name)
+	}
+
+	return NewFromBinReader(file, layout)
+}
+
+func Load(fsys fs.FS, name string, records interface{}) error {
+	table, err := Open(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	if err := table.Decode(records); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	return nil
+}
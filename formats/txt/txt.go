@@ -0,0 +1,176 @@
+package txt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	expansionRow = "Expansion"
+	maxLineSize  = 1 << 20
+)
+
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+func NewFromReader(reader io.Reader) (*Table, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+
+	table := new(Table)
+	for lineIndex := 0; scanner.Scan(); lineIndex++ {
+		fields := strings.Split(strings.TrimRight(scanner.Text(), "\r"), "\t")
+		if lineIndex == 0 {
+			table.Columns = fields
+			continue
+		}
+
+		if isSeparatorRow(fields) {
+			continue
+		}
+
+		row := make([]string, len(table.Columns))
+		copy(row, fields)
+		table.Rows = append(table.Rows, row)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(table.Columns) == 0 {
+		return nil, errors.New("table has no header row")
+	}
+
+	return table, nil
+}
+
+func isSeparatorRow(fields []string) bool {
+	for i, field := range fields {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(field), expansionRow) {
+			continue
+		}
+
+		if len(strings.TrimSpace(field)) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (t *Table) Column(name string) int {
+	for i, column := range t.Columns {
+		if column == name {
+			return i
+		}
+	}
+
+	for i, column := range t.Columns {
+		if strings.EqualFold(column, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (t *Table) Decode(records interface{}) error {
+	value := reflect.ValueOf(records)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Slice || value.Elem().Type().Elem().Kind() != reflect.Struct {
+		return errors.New("records must be a pointer to a slice of structs")
+	}
+
+	var (
+		slice      = value.Elem()
+		recordType = slice.Type().Elem()
+		fields     []int
+		columns    []int
+	)
+
+	for i := 0; i < recordType.NumField(); i++ {
+		field := recordType.Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		name := field.Tag.Get("txt")
+		if name == "-" {
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+
+		column := t.Column(name)
+		if column < 0 {
+			return fmt.Errorf("column %s not found", name)
+		}
+
+		fields = append(fields, i)
+		columns = append(columns, column)
+	}
+
+	result := reflect.MakeSlice(slice.Type(), len(t.Rows), len(t.Rows))
+	for i, row := range t.Rows {
+		record := result.Index(i)
+		for j, fieldIndex := range fields {
+			if err := decodeField(record.Field(fieldIndex), row[columns[j]]); err != nil {
+				return fmt.Errorf("row %d, column %s: %v", i+1, t.Columns[columns[j]], err)
+			}
+		}
+	}
+
+	slice.Set(result)
+	return nil
+}
+
+func decodeField(field reflect.Value, text string) error {
+	if field.Kind() == reflect.String {
+		field.SetString(text)
+		return nil
+	}
+
+	text = strings.TrimSpace(text)
+	if len(text) == 0 {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value, err := strconv.ParseInt(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(value)
+	case reflect.Float32, reflect.Float64:
+		value, err := strconv.ParseFloat(text, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(value)
+	case reflect.Bool:
+		value, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetBool(value != 0)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package txt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testTable = "Name\tLevel\tSpeed\tEnabled\t\tcode\r\n" +
+	"Skeleton\t1\t0.5\t1\t\tsk1\r\n" +
+	"Expansion\r\n" +
+	"\t\t\t\t\t\r\n" +
+	"Imp\t\t\t0\t\timp\r\n" +
+	"Zombie\t-3\r\n"
+
+type testRecord struct {
+	Name    string
+	Level   int     `txt:"Level"`
+	Speed   float64 `txt:"Speed"`
+	Enabled bool    `txt:"Enabled"`
+	Code    string  `txt:"code"`
+	Ignored int     `txt:"-"`
+	unused  int
+}
+
+func TestNewFromReader(t *testing.T) {
+	table, err := NewFromReader(strings.NewReader(testTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedColumns := []string{"Name", "Level", "Speed", "Enabled", "", "code"}
+	if !reflect.DeepEqual(table.Columns, expectedColumns) {
+		t.Errorf("columns: %q, expected: %q", table.Columns, expectedColumns)
+	}
+
+	if len(table.Rows) != 3 {
+		t.Fatalf("row count: %d", len(table.Rows))
+	}
+
+	if row := table.Rows[2]; len(row) != len(expectedColumns) || row[0] != "Zombie" || row[5] != "" {
+		t.Errorf("short row: %q", row)
+	}
+
+	if index := table.Column("CODE"); index != 5 {
+		t.Errorf("column index: %d", index)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	table, err := NewFromReader(strings.NewReader(testTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []testRecord
+	if err := table.Decode(&records); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []testRecord{
+		{Name: "Skeleton", Level: 1, Speed: 0.5, Enabled: true, Code: "sk1"},
+		{Name: "Imp", Code: "imp"},
+		{Name: "Zombie", Level: -3},
+	}
+
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("records: %+v, expected: %+v", records, expected)
+	}
+
+	var missing []struct {
+		Value int `txt:"missing"`
+	}
+	if err := table.Decode(&missing); err == nil {
+		t.Error("missing column was accepted")
+	}
+
+	var invalid []struct {
+		Name int `txt:"Name"`
+	}
+	if err := table.Decode(&invalid); err == nil {
+		t.Error("invalid integer was accepted")
+	}
+
+	if err := table.Decode(records); err == nil {
+		t.Error("non-pointer records were accepted")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/global/excel/monsters.txt": {Data: []byte(testTable)},
+		"data/global/excel/monstats.bin": {Data: append([]byte{0x01, 0x00, 0x00, 0x00}, make([]byte, 0x1a8)...)},
+	}
+
+	var records []testRecord
+	if err := Load(fsys, "data/global/excel/monsters.txt", &records); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 3 || records[2].Name != "Zombie" {
+		t.Errorf("records: %+v", records)
+	}
+
+	var monsters []struct {
+		Code  string `txt:"Code"`
+		Level int    `txt:"-"`
+	}
+
+	if err := Load(fsys, "data/global/excel/monstats.bin", &monsters); err != nil || len(monsters) != 1 {
+		t.Errorf("binary records: %+v, %v", monsters, err)
+	}
+
+	var levels []struct {
+		Level int `txt:"Level"`
+	}
+
+	if err := Load(fsys, "data/global/excel/monstats.bin", &levels); err == nil {
+		t.Error("unmapped binary column was accepted")
+	}
+
+	if err := Load(fsys, "data/global/excel/missing.txt", &records); err == nil {
+		t.Error("missing table was accepted")
+	}
+}
@@ -0,0 +1,140 @@
+package composite
+
+import (
+	"errors"
+
+	"github.com/FooSoft/lazarus/formats/cof"
+	"github.com/FooSoft/lazarus/formats/dat"
+	"github.com/FooSoft/lazarus/formats/dcc"
+	"github.com/FooSoft/lazarus/graphics/sprite"
+	"github.com/FooSoft/lazarus/math"
+)
+
+type Frame struct {
+	Size     math.Vec2i
+	Position math.Vec2i
+	Data     []math.Color4b
+}
+
+type Direction struct {
+	Frames []Frame
+}
+
+type Animation struct {
+	Directions []Direction
+}
+
+type Layers [cof.CompositeCount]*dcc.Sprite
+
+func Compose(anim *cof.Animation, layers *Layers, palette *dat.Palette) (*Animation, error) {
+	result := &Animation{Directions: make([]Direction, anim.Directions)}
+	for i := range result.Directions {
+		for j := 0; j < anim.FramesPerDir; j++ {
+			frame, err := composeFrame(anim, layers, palette, i, j)
+			if err != nil {
+				return nil, err
+			}
+
+			result.Directions[i].Frames = append(result.Directions[i].Frames, *frame)
+		}
+	}
+
+	return result, nil
+}
+
+func composeFrame(anim *cof.Animation, layers *Layers, palette *dat.Palette, directionIndex, frameIndex int) (*Frame, error) {
+	var (
+		frames  []dcc.Frame
+		options []dat.ColorOptions
+		bounds  math.Rect4i
+	)
+
+	for _, compositeType := range anim.Priority[directionIndex][frameIndex] {
+		layer := anim.Layer(compositeType)
+		layerSprite := layers[compositeType]
+		if layer == nil || layerSprite == nil {
+			continue
+		}
+
+		if directionIndex >= len(layerSprite.Directions) || frameIndex >= len(layerSprite.Directions[directionIndex].Frames) {
+			return nil, errors.New("layer sprite does not match animation")
+		}
+
+		frame := layerSprite.Directions[directionIndex].Frames[frameIndex]
+		if frame.Size.X == 0 || frame.Size.Y == 0 {
+			continue
+		}
+
+		var (
+			position = sprite.FramePosition(frame.Size, frame.Offset)
+			minX     = position.X
+			minY     = position.Y
+			maxX     = position.X + frame.Size.X
+			maxY     = position.Y + frame.Size.Y
+		)
+
+		if len(frames) > 0 {
+			if bounds.X < minX {
+				minX = bounds.X
+			}
+			if bounds.Y < minY {
+				minY = bounds.Y
+			}
+			if bounds.X+bounds.W > maxX {
+				maxX = bounds.X + bounds.W
+			}
+			if bounds.Y+bounds.H > maxY {
+				maxY = bounds.Y + bounds.H
+			}
+		}
+
+		bounds = math.Rect4i{X: minX, Y: minY, W: maxX - minX, H: maxY - minY}
+		frames = append(frames, frame)
+		options = append(options, layerOptions(layer))
+	}
+
+	result := &Frame{
+		Size:     math.Vec2i{X: bounds.W, Y: bounds.H},
+		Position: math.Vec2i{X: bounds.X, Y: bounds.Y},
+		Data:     make([]math.Color4b, bounds.W*bounds.H),
+	}
+
+	for i, frame := range frames {
+		var (
+			colors   = palette.ToRGBA(frame.Data, options[i])
+			position = sprite.FramePosition(frame.Size, frame.Offset)
+		)
+
+		for y := 0; y < frame.Size.Y; y++ {
+			for x := 0; x < frame.Size.X; x++ {
+				index := (position.Y-bounds.Y+y)*bounds.W + position.X - bounds.X + x
+				result.Data[index] = dat.BlendColors(colors[y*frame.Size.X+x], result.Data[index], options[i].Blend)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func layerOptions(layer *cof.Layer) dat.ColorOptions {
+	if !layer.Transparent {
+		return dat.ColorOptions{}
+	}
+
+	switch layer.DrawEffect {
+	case cof.DrawEffectAlpha25:
+		return dat.ColorOptions{Blend: dat.BlendAlpha25}
+	case cof.DrawEffectAlpha50:
+		return dat.ColorOptions{Blend: dat.BlendAlpha50}
+	case cof.DrawEffectAlpha75:
+		return dat.ColorOptions{Blend: dat.BlendAlpha75}
+	case cof.DrawEffectModulate:
+		return dat.ColorOptions{Blend: dat.BlendMultiply}
+	case cof.DrawEffectMod2XTrans, cof.DrawEffectMod2X:
+		return dat.ColorOptions{Blend: dat.BlendMultiply2X}
+	case cof.DrawEffectBurn:
+		return dat.ColorOptions{Blend: dat.BlendAdditive}
+	default:
+		return dat.ColorOptions{}
+	}
+}
@@ -0,0 +1,87 @@
+package composite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/FooSoft/lazarus/formats/cof"
+	"github.com/FooSoft/lazarus/formats/dat"
+	"github.com/FooSoft/lazarus/formats/dcc"
+	"github.com/FooSoft/lazarus/math"
+)
+
+func TestCompose(t *testing.T) {
+	anim := &cof.Animation{
+		Directions:   1,
+		FramesPerDir: 2,
+		Layers: []cof.Layer{
+			{Type: cof.CompositeHead},
+			{Type: cof.CompositeTorso},
+			{Type: cof.CompositeRightHand, Transparent: true, DrawEffect: cof.DrawEffectAlpha50},
+		},
+		Priority: [][][]int{{
+			{cof.CompositeHead, cof.CompositeTorso, cof.CompositeRightHand},
+			{cof.CompositeRightHand, cof.CompositeTorso, cof.CompositeHead},
+		}},
+	}
+
+	torso := dcc.Frame{Size: math.Vec2i{X: 2, Y: 2}, Offset: math.Vec2i{X: 0, Y: 1}, Data: []byte{1, 1, 1, 1}}
+	hand := dcc.Frame{Size: math.Vec2i{X: 2, Y: 1}, Offset: math.Vec2i{X: 1, Y: 0}, Data: []byte{2, 0}}
+
+	layers := new(Layers)
+	layers[cof.CompositeTorso] = &dcc.Sprite{Directions: []dcc.Direction{{Frames: []dcc.Frame{torso, torso}}}}
+	layers[cof.CompositeRightHand] = &dcc.Sprite{Directions: []dcc.Direction{{Frames: []dcc.Frame{hand, hand}}}}
+
+	palette := new(dat.Palette)
+	palette.Colors[1] = math.Color3b{R: 0xff}
+	palette.Colors[2] = math.Color3b{B: 0xff}
+
+	result, err := Compose(anim, layers, palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		red   = math.Color4b{R: 0xff, A: 0xff}
+		mixed = math.Color4b{R: 0x7f, B: 0x80, A: 0xff}
+		clear = math.Color4b{}
+	)
+
+	expected := []Frame{
+		{math.Vec2i{X: 3, Y: 2}, math.Vec2i{}, []math.Color4b{red, mixed, clear, red, red, clear}},
+		{math.Vec2i{X: 3, Y: 2}, math.Vec2i{}, []math.Color4b{red, red, clear, red, red, clear}},
+	}
+
+	if len(result.Directions) != 1 || !reflect.DeepEqual(result.Directions[0].Frames, expected) {
+		t.Errorf("composited frames: %+v, expected: %+v", result.Directions, expected)
+	}
+
+	layers[cof.CompositeTorso] = &dcc.Sprite{Directions: []dcc.Direction{{Frames: []dcc.Frame{torso}}}}
+	if _, err := Compose(anim, layers, palette); err == nil {
+		t.Error("mismatched layer sprite was accepted")
+	}
+}
+
+func TestLayerOptions(t *testing.T) {
+	expected := map[int]dat.BlendMode{
+		0: dat.BlendAlpha25,
+		1: dat.BlendAlpha50,
+		2: dat.BlendAlpha75,
+		3: dat.BlendMultiply,
+		4: dat.BlendAdditive,
+		5: dat.BlendNone,
+		6: dat.BlendMultiply2X,
+		7: dat.BlendMultiply2X,
+		8: dat.BlendNone,
+	}
+
+	for effect, blend := range expected {
+		if options := layerOptions(&cof.Layer{Transparent: true, DrawEffect: effect}); options.Blend != blend {
+			t.Errorf("draw effect %d: %d, expected: %d", effect, options.Blend, blend)
+		}
+	}
+
+	if options := layerOptions(&cof.Layer{DrawEffect: cof.DrawEffectBurn}); options.Blend != dat.BlendNone {
+		t.Errorf("opaque layer blend: %d", options.Blend)
+	}
+}
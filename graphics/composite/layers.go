@@ -0,0 +1,56 @@
+package composite
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/FooSoft/lazarus/formats/cof"
+	"github.com/FooSoft/lazarus/formats/dcc"
+	"github.com/FooSoft/lazarus/platform"
+)
+
+type Equipment [cof.CompositeCount]string
+
+func LoadAnimation(basePath, token, mode, weaponClass string) (*cof.Animation, error) {
+	file, err := platform.FileOpen(path.Join(basePath, token, "cof", token+mode+weaponClass+".cof"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return cof.NewFromReader(file)
+}
+
+func LoadLayers(anim *cof.Animation, basePath, token, mode string, equipment *Equipment) (*Layers, error) {
+	layers := new(Layers)
+	for _, layer := range anim.Layers {
+		code := equipment[layer.Type]
+		if len(code) == 0 {
+			continue
+		}
+
+		var (
+			compositeCode = cof.CompositeCodes[layer.Type]
+			spritePath    = path.Join(basePath, token, compositeCode, token+compositeCode+code+mode+layer.WeaponClass+".dcc")
+		)
+
+		sprite, err := loadSprite(spritePath)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", spritePath, err)
+		}
+
+		layers[layer.Type] = sprite
+	}
+
+	return layers, nil
+}
+
+func loadSprite(spritePath string) (*dcc.Sprite, error) {
+	file, err := platform.FileOpen(spritePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return dcc.NewFromReader(file)
+}
@@ -0,0 +1,181 @@
+package sprite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/gif"
+	"image/png"
+	"io"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+)
+
+const (
+	apngDisposeBackground = 1
+	apngBlendSource       = 0
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+func (d *Direction) WriteGif(writer io.Writer, palette *dat.Palette, frameRate int) error {
+	if frameRate <= 0 {
+		return errors.New("frame rate must be positive")
+	}
+
+	delay := 100 / frameRate
+	if delay < 1 {
+		delay = 1
+	}
+
+	anim := &gif.GIF{BackgroundIndex: 0}
+	for _, img := range d.Images(palette) {
+		anim.Image = append(anim.Image, img)
+		anim.Delay = append(anim.Delay, delay)
+		anim.Disposal = append(anim.Disposal, gif.DisposalBackground)
+	}
+
+	return gif.EncodeAll(writer, anim)
+}
+
+func (d *Direction) WriteApng(writer io.Writer, palette *dat.Palette, frameRate int) error {
+	if frameRate <= 0 {
+		return errors.New("frame rate must be positive")
+	}
+
+	images := d.Images(palette)
+	if len(images) == 0 {
+		return errors.New("direction has no frames")
+	}
+
+	if _, err := writer.Write(pngSignature); err != nil {
+		return err
+	}
+
+	var sequence uint32
+	for i, img := range images {
+		chunks, err := encodePngChunks(img)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			if err := writeApngHeader(writer, chunks, len(images)); err != nil {
+				return err
+			}
+		}
+
+		bounds := img.Bounds()
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], sequence)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(bounds.Dx()))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(bounds.Dy()))
+		binary.BigEndian.PutUint16(fctl[20:], 1)
+		binary.BigEndian.PutUint16(fctl[22:], uint16(frameRate))
+		fctl[24] = apngDisposeBackground
+		fctl[25] = apngBlendSource
+		sequence++
+
+		if err := writePngChunk(writer, pngChunk{"fcTL", fctl}); err != nil {
+			return err
+		}
+
+		for _, chunk := range chunks {
+			if chunk.kind != "IDAT" {
+				continue
+			}
+
+			if i == 0 {
+				if err := writePngChunk(writer, chunk); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			fdat := make([]byte, 4+len(chunk.data))
+			binary.BigEndian.PutUint32(fdat, sequence)
+			copy(fdat[4:], chunk.data)
+			sequence++
+
+			if err := writePngChunk(writer, pngChunk{"fdAT", fdat}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writePngChunk(writer, pngChunk{"IEND", nil})
+}
+
+func writeApngHeader(writer io.Writer, chunks []pngChunk, frameCount int) error {
+	for _, chunk := range chunks {
+		switch chunk.kind {
+		case "IHDR":
+			if err := writePngChunk(writer, chunk); err != nil {
+				return err
+			}
+
+			actl := make([]byte, 8)
+			binary.BigEndian.PutUint32(actl, uint32(frameCount))
+			if err := writePngChunk(writer, pngChunk{"acTL", actl}); err != nil {
+				return err
+			}
+		case "PLTE", "tRNS":
+			if err := writePngChunk(writer, chunk); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func encodePngChunks(img image.Image) ([]pngChunk, error) {
+	var buff bytes.Buffer
+	if err := png.Encode(&buff, img); err != nil {
+		return nil, err
+	}
+
+	data := buff.Bytes()[len(pngSignature):]
+
+	var chunks []pngChunk
+	for len(data) >= 12 {
+		length := binary.BigEndian.Uint32(data)
+		if uint32(len(data)-12) < length {
+			return nil, errors.New("invalid png chunk")
+		}
+
+		chunks = append(chunks, pngChunk{string(data[4:8]), data[8 : 8+length]})
+		data = data[12+length:]
+	}
+
+	return chunks, nil
+}
+
+func writePngChunk(writer io.Writer, chunk pngChunk) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(chunk.data)))
+	copy(header[4:], chunk.kind)
+
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(chunk.data)
+
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+
+	for _, data := range [][]byte{header, chunk.data, footer} {
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package sprite
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+)
+
+func (f *Frame) Image(palette *dat.Palette) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, f.Size.X, f.Size.Y))
+	for i, colorSrc := range palette.ToRGBA(f.Data, dat.ColorOptions{}) {
+		img.Pix[i*4+0] = colorSrc.R
+		img.Pix[i*4+1] = colorSrc.G
+		img.Pix[i*4+2] = colorSrc.B
+		img.Pix[i*4+3] = colorSrc.A
+	}
+
+	return img
+}
+
+func (s *Sprite) Export(basePath string, palette *dat.Palette, mode string, frameRate int) error {
+	switch mode {
+	case "frames":
+		for di := range s.Directions {
+			for fi := range s.Directions[di].Frames {
+				img := s.Directions[di].Frames[fi].Image(palette)
+				if err := writeFile(fmt.Sprintf("%s_%d_%d.png", basePath, di, fi), func(w io.Writer) error { return png.Encode(w, img) }); err != nil {
+					return err
+				}
+			}
+		}
+	case "sheet":
+		img, atlas := s.Sheet(palette)
+		if err := writeFile(basePath+".png", func(w io.Writer) error { return png.Encode(w, img) }); err != nil {
+			return err
+		}
+
+		return writeFile(basePath+".json", func(w io.Writer) error {
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(atlas)
+		})
+	case "gif":
+		for di := range s.Directions {
+			direction := &s.Directions[di]
+			if err := writeFile(fmt.Sprintf("%s_%d.gif", basePath, di), func(w io.Writer) error { return direction.WriteGif(w, palette, frameRate) }); err != nil {
+				return err
+			}
+		}
+	case "apng":
+		for di := range s.Directions {
+			direction := &s.Directions[di]
+			if err := writeFile(fmt.Sprintf("%s_%d.png", basePath, di), func(w io.Writer) error { return direction.WriteApng(w, palette, frameRate) }); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported export mode %q", mode)
+	}
+
+	return nil
+}
+
+func writeFile(path string, write func(w io.Writer) error) error {
+	fp, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := write(fp); err != nil {
+		fp.Close()
+		return err
+	}
+
+	return fp.Close()
+}
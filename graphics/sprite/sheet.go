@@ -0,0 +1,73 @@
+package sprite
+
+import (
+	"image"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+	"github.com/FooSoft/lazarus/math"
+)
+
+type Atlas struct {
+	Width  int          `json:"width"`
+	Height int          `json:"height"`
+	Frames []AtlasFrame `json:"frames"`
+}
+
+type AtlasFrame struct {
+	Direction int `json:"direction"`
+	Frame     int `json:"frame"`
+	X         int `json:"x"`
+	Y         int `json:"y"`
+	Width     int `json:"width"`
+	Height    int `json:"height"`
+	OriginX   int `json:"originX"`
+	OriginY   int `json:"originY"`
+	OffsetX   int `json:"offsetX"`
+	OffsetY   int `json:"offsetY"`
+}
+
+func (s *Sprite) Sheet(palette *dat.Palette) (*image.Paletted, *Atlas) {
+	var (
+		atlas      = new(Atlas)
+		rowOffsets []int
+	)
+
+	for _, direction := range s.Directions {
+		bounds := direction.Bounds()
+		if width := bounds.W * len(direction.Frames); width > atlas.Width {
+			atlas.Width = width
+		}
+
+		rowOffsets = append(rowOffsets, atlas.Height)
+		atlas.Height += bounds.H
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, atlas.Width, atlas.Height), Palette(palette))
+	for di, direction := range s.Directions {
+		bounds := direction.Bounds()
+		for fi, frame := range direction.Frames {
+			atlasFrame := AtlasFrame{
+				Direction: di,
+				Frame:     fi,
+				X:         fi * bounds.W,
+				Y:         rowOffsets[di],
+				Width:     bounds.W,
+				Height:    bounds.H,
+				OriginX:   -bounds.X,
+				OriginY:   -bounds.Y,
+				OffsetX:   frame.Offset.X,
+				OffsetY:   frame.Offset.Y,
+			}
+
+			position := math.Vec2i{
+				X: atlasFrame.X + atlasFrame.OriginX + frame.Position.X,
+				Y: atlasFrame.Y + atlasFrame.OriginY + frame.Position.Y,
+			}
+
+			drawFrame(img, frame, position)
+			atlas.Frames = append(atlas.Frames, atlasFrame)
+		}
+	}
+
+	return img, atlas
+}
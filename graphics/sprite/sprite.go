@@ -0,0 +1,94 @@
+package sprite
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+	"github.com/FooSoft/lazarus/math"
+)
+
+type Frame struct {
+	Size     math.Vec2i
+	Offset   math.Vec2i
+	Position math.Vec2i
+	Data     []byte
+}
+
+type Direction struct {
+	Frames []Frame
+}
+
+type Sprite struct {
+	Directions []Direction
+}
+
+func NewFrame(size, offset math.Vec2i, data []byte) Frame {
+	return Frame{Size: size, Offset: offset, Position: FramePosition(size, offset), Data: data}
+}
+
+func FramePosition(size, offset math.Vec2i) math.Vec2i {
+	return math.Vec2i{X: offset.X, Y: offset.Y - size.Y + 1}
+}
+
+func (d *Direction) Bounds() math.Rect4i {
+	var minX, minY, maxX, maxY int
+	for i, frame := range d.Frames {
+		if i == 0 || frame.Position.X < minX {
+			minX = frame.Position.X
+		}
+		if i == 0 || frame.Position.Y < minY {
+			minY = frame.Position.Y
+		}
+		if i == 0 || frame.Position.X+frame.Size.X > maxX {
+			maxX = frame.Position.X + frame.Size.X
+		}
+		if i == 0 || frame.Position.Y+frame.Size.Y > maxY {
+			maxY = frame.Position.Y + frame.Size.Y
+		}
+	}
+
+	return math.Rect4i{X: minX, Y: minY, W: maxX - minX, H: maxY - minY}
+}
+
+func (d *Direction) Images(palette *dat.Palette) []*image.Paletted {
+	var (
+		bounds   = d.Bounds()
+		colors   = Palette(palette)
+		images   []*image.Paletted
+		rect     = image.Rect(0, 0, bounds.W, bounds.H)
+		position = math.Vec2i{X: bounds.X, Y: bounds.Y}
+	)
+
+	for _, frame := range d.Frames {
+		img := image.NewPaletted(rect, colors)
+		drawFrame(img, frame, math.Vec2i{X: frame.Position.X - position.X, Y: frame.Position.Y - position.Y})
+		images = append(images, img)
+	}
+
+	return images
+}
+
+func Palette(palette *dat.Palette) color.Palette {
+	indices := make([]byte, len(palette.Colors))
+	for i := range indices {
+		indices[i] = byte(i)
+	}
+
+	colors := make(color.Palette, len(indices))
+	for i, colorSrc := range palette.ToRGBA(indices, dat.ColorOptions{}) {
+		colors[i] = color.NRGBA{colorSrc.R, colorSrc.G, colorSrc.B, colorSrc.A}
+	}
+
+	return colors
+}
+
+func drawFrame(img *image.Paletted, frame Frame, position math.Vec2i) {
+	for y := 0; y < frame.Size.Y; y++ {
+		for x := 0; x < frame.Size.X; x++ {
+			if index := frame.Data[y*frame.Size.X+x]; index != 0 {
+				img.SetColorIndex(position.X+x, position.Y+y, index)
+			}
+		}
+	}
+}
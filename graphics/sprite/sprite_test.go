@@ -0,0 +1,99 @@
+package sprite
+
+import (
+	"bytes"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+	"github.com/FooSoft/lazarus/math"
+)
+
+func TestNewFrame(t *testing.T) {
+	frame := NewFrame(math.Vec2i{X: 3, Y: 4}, math.Vec2i{X: -1, Y: 2}, make([]byte, 12))
+	if expected := (math.Vec2i{X: -1, Y: -1}); frame.Position != expected {
+		t.Errorf("position: %+v, expected: %+v", frame.Position, expected)
+	}
+
+	direction := Direction{Frames: []Frame{
+		frame,
+		NewFrame(math.Vec2i{X: 2, Y: 1}, math.Vec2i{X: 1, Y: 0}, make([]byte, 2)),
+	}}
+
+	if bounds, expected := direction.Bounds(), (math.Rect4i{X: -1, Y: -1, W: 4, H: 4}); bounds != expected {
+		t.Errorf("bounds: %+v, expected: %+v", bounds, expected)
+	}
+}
+
+func TestWriteGif(t *testing.T) {
+	direction := Direction{Frames: []Frame{
+		NewFrame(math.Vec2i{X: 2, Y: 2}, math.Vec2i{X: 0, Y: 1}, []byte{1, 0, 0, 1}),
+		NewFrame(math.Vec2i{X: 2, Y: 2}, math.Vec2i{X: 0, Y: 1}, []byte{0, 1, 1, 0}),
+	}}
+
+	for frameRate, delay := range map[int]int{10: 10, 25: 4, 100: 1, 200: 1} {
+		var buffer bytes.Buffer
+		if err := direction.WriteGif(&buffer, dat.NewFromGrayscale(), frameRate); err != nil {
+			t.Fatal(err)
+		}
+
+		anim, err := gif.DecodeAll(&buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(anim.Delay) != 2 || anim.Delay[0] != delay || anim.Delay[1] != delay {
+			t.Errorf("frame rate %d delays: %v, expected: %d", frameRate, anim.Delay, delay)
+		}
+	}
+
+	if err := direction.WriteGif(new(bytes.Buffer), dat.NewFromGrayscale(), 0); err == nil {
+		t.Error("zero frame rate was accepted")
+	}
+}
+
+func TestExport(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		sprite = &Sprite{Directions: []Direction{
+			{Frames: []Frame{NewFrame(math.Vec2i{X: 2, Y: 1}, math.Vec2i{}, []byte{0, 7})}},
+			{Frames: []Frame{NewFrame(math.Vec2i{X: 1, Y: 1}, math.Vec2i{}, []byte{3}), NewFrame(math.Vec2i{X: 1, Y: 1}, math.Vec2i{}, []byte{4})}},
+		}}
+	)
+
+	if err := sprite.Export(filepath.Join(dir, "test"), dat.NewFromGrayscale(), "frames", 15); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"test_0_0.png", "test_1_0.png", "test_1_1.png"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	fp, err := os.Open(filepath.Join(dir, "test_0_0.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+
+	img, err := png.Decode(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bounds := img.Bounds(); bounds.Dx() != 2 || bounds.Dy() != 1 {
+		t.Errorf("frame bounds: %v", bounds)
+	}
+
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Error("transparent pixel is opaque")
+	}
+
+	if err := sprite.Export(filepath.Join(dir, "test"), dat.NewFromGrayscale(), "bmp", 15); err == nil {
+		t.Error("unsupported export mode was accepted")
+	}
+}
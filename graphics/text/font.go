@@ -0,0 +1,135 @@
+package text
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+	"github.com/FooSoft/lazarus/formats/dc6"
+	"github.com/FooSoft/lazarus/formats/tbl"
+	"github.com/FooSoft/lazarus/math"
+)
+
+const fontDir = "data/local/font"
+
+var fontSignature = [4]byte{'W', 'o', 'o', '!'}
+
+var DefaultColors = [tbl.ColorPurple + 1]math.Color3b{
+	tbl.ColorWhite:     {R: 0xff, G: 0xff, B: 0xff},
+	tbl.ColorRed:       {R: 0xff, G: 0x4d, B: 0x4d},
+	tbl.ColorGreen:     {R: 0x00, G: 0xff, B: 0x00},
+	tbl.ColorBlue:      {R: 0x69, G: 0x69, B: 0xff},
+	tbl.ColorGold:      {R: 0xc7, G: 0xb3, B: 0x77},
+	tbl.ColorGray:      {R: 0x69, G: 0x69, B: 0x69},
+	tbl.ColorBlack:     {R: 0x00, G: 0x00, B: 0x00},
+	tbl.ColorTan:       {R: 0xd0, G: 0xc2, B: 0x7d},
+	tbl.ColorOrange:    {R: 0xff, G: 0xa8, B: 0x00},
+	tbl.ColorYellow:    {R: 0xff, G: 0xff, B: 0x64},
+	tbl.ColorDarkGreen: {R: 0x00, G: 0x80, B: 0x00},
+	tbl.ColorPurple:    {R: 0xae, G: 0x00, B: 0xff},
+}
+
+type fileHeader struct {
+	Signature  [4]byte
+	Version    uint16
+	Locale     uint32
+	LineHeight uint8
+	CapHeight  uint8
+}
+
+type glyphData struct {
+	Char   uint16
+	_      uint8
+	Width  uint8
+	Height uint8
+	_      uint8
+	_      uint16
+	Frame  uint16
+	_      uint32
+}
+
+type Glyph struct {
+	Size  math.Vec2i
+	Frame int
+}
+
+type Font struct {
+	Frames     []dc6.Frame
+	Glyphs     map[rune]Glyph
+	LineHeight int
+	CapHeight  int
+	Kerning    int
+	Palette    *dat.Palette
+	Colors     [tbl.ColorPurple + 1]math.Color3b
+}
+
+func NewFont(sprite io.ReadSeeker, metrics io.Reader, palette *dat.Palette) (*Font, error) {
+	animation, err := dc6.NewFromReader(sprite)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(animation.Directions) == 0 {
+		return nil, errors.New("font sprite has no frames")
+	}
+
+	var fileHead fileHeader
+	if err := binary.Read(metrics, binary.LittleEndian, &fileHead); err != nil {
+		return nil, err
+	}
+
+	if fileHead.Signature != fontSignature {
+		return nil, errors.New("invalid font table signature")
+	}
+
+	font := &Font{
+		Frames:     animation.Directions[0].Frames,
+		Glyphs:     make(map[rune]Glyph),
+		LineHeight: int(fileHead.LineHeight),
+		CapHeight:  int(fileHead.CapHeight),
+		Palette:    palette,
+		Colors:     DefaultColors,
+	}
+
+	for {
+		var glyph glyphData
+		if err := binary.Read(metrics, binary.LittleEndian, &glyph); err != nil {
+			if err == io.EOF {
+				break
+			}
+
+			return nil, err
+		}
+
+		if int(glyph.Frame) >= len(font.Frames) {
+			return nil, errors.New("glyph frame out of range")
+		}
+
+		font.Glyphs[rune(glyph.Char)] = Glyph{
+			Size:  math.Vec2i{X: int(glyph.Width), Y: int(glyph.Height)},
+			Frame: int(glyph.Frame),
+		}
+	}
+
+	return font, nil
+}
+
+func LoadFont(fsys fs.FS, language, name string, palette *dat.Palette) (*Font, error) {
+	basePath := path.Join(fontDir, language, name)
+
+	sprite, err := fs.ReadFile(fsys, basePath+".dc6")
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := fs.ReadFile(fsys, basePath+".tbl")
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFont(bytes.NewReader(sprite), bytes.NewReader(metrics), palette)
+}
@@ -0,0 +1,116 @@
+package text
+
+import (
+	"github.com/FooSoft/lazarus/formats/tbl"
+	"github.com/FooSoft/lazarus/math"
+)
+
+type PlacedGlyph struct {
+	Glyph    Glyph
+	Position math.Vec2i
+	Color    int
+}
+
+type Layout struct {
+	Size   math.Vec2i
+	Glyphs []PlacedGlyph
+}
+
+type layoutChar struct {
+	char  rune
+	color int
+}
+
+func (f *Font) Layout(text string, maxWidth int) *Layout {
+	var (
+		lines   [][]layoutChar
+		line    []layoutChar
+		width   int
+		breakAt = -1
+	)
+
+	for _, segment := range tbl.Colorize(text, tbl.ColorWhite) {
+		for _, char := range segment.Text {
+			if char == '\n' {
+				lines = append(lines, line)
+				line, width, breakAt = nil, 0, -1
+				continue
+			}
+
+			advance := f.advance(char)
+			if maxWidth > 0 && width+advance > maxWidth && len(line) > 0 {
+				if char == ' ' {
+					lines = append(lines, line)
+					line, width, breakAt = nil, 0, -1
+					continue
+				}
+
+				if breakAt >= 0 {
+					lines = append(lines, line[:breakAt])
+					line = append([]layoutChar(nil), line[breakAt+1:]...)
+				} else {
+					lines = append(lines, line)
+					line = nil
+				}
+
+				width, breakAt = f.measure(line), -1
+			}
+
+			if char == ' ' {
+				breakAt = len(line)
+			}
+
+			line = append(line, layoutChar{char, segment.Color})
+			width += advance
+		}
+	}
+
+	lines = append(lines, line)
+
+	layout := &Layout{Size: math.Vec2i{Y: len(lines) * f.LineHeight}}
+	for i, line := range lines {
+		x := 0
+		for _, char := range line {
+			glyph, ok := f.Glyphs[char.char]
+			if !ok {
+				continue
+			}
+
+			position := math.Vec2i{X: x, Y: i * f.LineHeight}
+			layout.Glyphs = append(layout.Glyphs, PlacedGlyph{glyph, position, char.color})
+			x += glyph.Size.X + f.Kerning
+		}
+
+		if x > 0 {
+			x -= f.Kerning
+		}
+
+		if x > layout.Size.X {
+			layout.Size.X = x
+		}
+	}
+
+	return layout
+}
+
+func (f *Font) Measure(text string, maxWidth int) math.Vec2i {
+	return f.Layout(text, maxWidth).Size
+}
+
+func (f *Font) advance(char rune) int {
+	glyph, ok := f.Glyphs[char]
+	if !ok {
+		return 0
+	}
+
+	return glyph.Size.X + f.Kerning
+}
+
+func (f *Font) measure(line []layoutChar) int {
+	var width int
+	for _, char := range line {
+		width += f.advance(char.char)
+	}
+
+	return width
+}
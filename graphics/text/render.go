@@ -0,0 +1,52 @@
+package text
+
+import (
+	"github.com/FooSoft/lazarus/graphics"
+	"github.com/FooSoft/lazarus/math"
+	"github.com/FooSoft/lazarus/platform"
+)
+
+func (f *Font) Render(text string, maxWidth int) ([]math.Color4b, math.Vec2i) {
+	var (
+		layout = f.Layout(text, maxWidth)
+		size   = layout.Size
+		colors = make([]math.Color4b, size.X*size.Y)
+	)
+
+	for _, placed := range layout.Glyphs {
+		var (
+			frame = f.Frames[placed.Glyph.Frame]
+			tint  = f.Colors[placed.Color]
+			top   = placed.Position.Y + f.LineHeight - frame.Size.Y
+		)
+
+		for y := 0; y < frame.Size.Y; y++ {
+			for x := 0; x < frame.Size.X; x++ {
+				index := frame.Data[y*frame.Size.X+x]
+				if index == 0 {
+					continue
+				}
+
+				positionX, positionY := placed.Position.X+x, top+y
+				if positionX < 0 || positionX >= size.X || positionY < 0 || positionY >= size.Y {
+					continue
+				}
+
+				color := f.Palette.Colors[index]
+				colors[positionY*size.X+positionX] = math.Color4b{
+					R: byte(int(color.R) * int(tint.R) / 0xff),
+					G: byte(int(color.G) * int(tint.G) / 0xff),
+					B: byte(int(color.B) * int(tint.B) / 0xff),
+					A: 0xff,
+				}
+			}
+		}
+	}
+
+	return colors, size
+}
+
+func (f *Font) Texture(text string, maxWidth int) (graphics.Texture, error) {
+	colors, size := f.Render(text, maxWidth)
+	return platform.NewTextureFromRgba(colors, size)
+}
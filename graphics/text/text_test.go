@@ -0,0 +1,127 @@
+package text
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"testing/fstest"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+	"github.com/FooSoft/lazarus/formats/dc6"
+	"github.com/FooSoft/lazarus/formats/tbl"
+	"github.com/FooSoft/lazarus/math"
+)
+
+func buildFont(t *testing.T) ([]byte, []byte) {
+	glyphs := []struct {
+		char  rune
+		width int
+	}{{'a', 2}, {'b', 3}, {' ', 1}}
+
+	var direction dc6.Direction
+	for i, glyph := range glyphs {
+		data := make([]byte, glyph.width*3)
+		if glyph.char != ' ' {
+			for j := range data {
+				data[j] = byte(i + 1)
+			}
+		}
+
+		direction.Frames = append(direction.Frames, dc6.Frame{Size: math.Vec2i{X: glyph.width, Y: 3}, Data: data})
+	}
+
+	var sprite bytes.Buffer
+	if err := (&dc6.Animation{Directions: []dc6.Direction{direction}}).Write(&sprite); err != nil {
+		t.Fatal(err)
+	}
+
+	var metrics bytes.Buffer
+	binary.Write(&metrics, binary.LittleEndian, fileHeader{Signature: fontSignature, Version: 1, LineHeight: 4, CapHeight: 3})
+	for i, glyph := range glyphs {
+		binary.Write(&metrics, binary.LittleEndian, glyphData{Char: uint16(glyph.char), Width: uint8(glyph.width), Height: 3, Frame: uint16(i)})
+	}
+
+	return sprite.Bytes(), metrics.Bytes()
+}
+
+func newTestFont(t *testing.T) *Font {
+	sprite, metrics := buildFont(t)
+
+	palette := new(dat.Palette)
+	palette.Colors[1] = math.Color3b{R: 0xff, G: 0xff, B: 0xff}
+	palette.Colors[2] = math.Color3b{R: 0x80, G: 0x80, B: 0x80}
+
+	font, err := LoadFont(fstest.MapFS{
+		"data/local/font/latin/font16.dc6": {Data: sprite},
+		"data/local/font/latin/font16.tbl": {Data: metrics},
+	}, "latin", "font16", palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return font
+}
+
+func TestLayout(t *testing.T) {
+	font := newTestFont(t)
+	if font.LineHeight != 4 || font.CapHeight != 3 || len(font.Glyphs) != 3 {
+		t.Fatalf("font: %+v", font)
+	}
+
+	layout := font.Layout("ab ba\nb", 0)
+	if layout.Size != (math.Vec2i{X: 11, Y: 8}) || len(layout.Glyphs) != 6 {
+		t.Errorf("layout: %+v", layout)
+	}
+
+	if last := layout.Glyphs[5]; last.Position != (math.Vec2i{X: 0, Y: 4}) {
+		t.Errorf("last glyph: %+v", last)
+	}
+
+	font.Kerning = 1
+	if size := font.Measure("ab ba", 0); size != (math.Vec2i{X: 15, Y: 4}) {
+		t.Errorf("kerned size: %+v", size)
+	}
+
+	font.Kerning = 0
+	layout = font.Layout("ab ab ab", 7)
+	if layout.Size != (math.Vec2i{X: 5, Y: 12}) {
+		t.Errorf("wrapped layout: %+v", layout)
+	}
+
+	for i, glyph := range layout.Glyphs {
+		if expected := (i / 2) * 4; glyph.Position.Y != expected {
+			t.Errorf("wrapped glyph %d: %+v", i, glyph)
+		}
+	}
+
+	layout = font.Layout("aaaa", 5)
+	if layout.Size != (math.Vec2i{X: 4, Y: 8}) {
+		t.Errorf("broken word layout: %+v", layout)
+	}
+
+	layout = font.Layout("aÿc1b", 0)
+	if len(layout.Glyphs) != 2 || layout.Glyphs[0].Color != tbl.ColorWhite || layout.Glyphs[1].Color != tbl.ColorRed {
+		t.Errorf("colored layout: %+v", layout)
+	}
+}
+
+func TestRender(t *testing.T) {
+	font := newTestFont(t)
+
+	colors, size := font.Render("aÿc1b", 0)
+	if size != (math.Vec2i{X: 5, Y: 4}) {
+		t.Fatalf("render size: %+v", size)
+	}
+
+	if color := colors[0]; color != (math.Color4b{}) {
+		t.Errorf("top row color: %+v", color)
+	}
+
+	if color := colors[size.X]; color != (math.Color4b{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Errorf("white glyph color: %+v", color)
+	}
+
+	if color := colors[size.X+2]; color != (math.Color4b{R: 0x80, G: 0x26, B: 0x26, A: 0xff}) {
+		t.Errorf("red glyph color: %+v", color)
+	}
+}
@@ -2,68 +2,184 @@ package platform
 
 import (
 	"errors"
+	"io"
+	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/FooSoft/lazarus/formats/mpq"
 )
 
+var (
+	ErrFileNotFound = errors.New("file not found")
+)
+
+type fileMount struct {
+	sourcePath string
+	archive    *mpq.Archive
+	paths      map[string]string
+	priority   int
+}
+
+func (m *fileMount) open(sourceResPath string) (io.ReadSeekCloser, error) {
+	if m.archive != nil {
+		return m.archive.OpenFile(sourceResPath)
+	}
+
+	return os.Open(sourceResPath)
+}
+
+func (m *fileMount) size(sourceResPath string) (int64, error) {
+	var (
+		info os.FileInfo
+		err  error
+	)
+
+	if m.archive != nil {
+		info, err = m.archive.Stat(filepath.ToSlash(sourceResPath))
+	} else {
+		info, err = os.Stat(sourceResPath)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size(), nil
+}
+
+func (m *fileMount) close() error {
+	if m.archive != nil {
+		return m.archive.Close()
+	}
+
+	return nil
+}
+
 var fileState struct {
-	mountPoints map[string]*mpq.Archive
-	mountPaths  map[string]*mpq.Archive
+	mounts []*fileMount
+	dirs   map[string][]string
+}
+
+type File struct {
+	handle io.ReadSeekCloser
+}
+
+func (f *File) Read(data []byte) (int, error) {
+	return f.handle.Read(data)
 }
 
-type File struct{}
+func (f *File) Seek(offset int64, whence int) (int64, error) {
+	return f.handle.Seek(offset, whence)
+}
+
+func (f *File) Close() error {
+	return f.handle.Close()
+}
 
 func FileMountArchive(mountPath, archivePath string) error {
+	if fileFindMount(archivePath) >= 0 {
+		return errors.New("file archive is already mounted")
+	}
+
 	archive, err := mpq.NewFromFile(archivePath)
 	if err != nil {
 		return err
 	}
 
-	if fileState.mountPoints == nil {
-		fileState.mountPoints = make(map[string]*mpq.Archive)
+	mount := &fileMount{
+		sourcePath: archivePath,
+		archive:    archive,
+		paths:      make(map[string]string),
 	}
 
-	var count int
-	for _, path := range archive.GetPaths() {
-		resourcePath := filepath.Join(mountPath, path)
-		if _, ok := fileState.mountPoints[resourcePath]; !ok {
-			fileState.mountPoints[resourcePath] = archive
-			count++
-		}
+	for _, archiveResPath := range archive.GetPaths() {
+		resourcePath := fileNormalizePath(mountPath + "/" + archiveResPath)
+		mount.paths[resourcePath] = archiveResPath
 	}
 
-	if count == 0 {
+	if len(mount.paths) == 0 {
 		archive.Close()
 		return errors.New("file archive could not be mounted")
 	}
 
+	fileAddMount(mount)
 	return nil
 }
 
-func FileUnmountArchive(mountPath string) error {
-	archive, ok := fileState.mountPoints[mountPath]
-	if !ok {
-		return errors.New("file archive is not mounted")
+func FileMountDirectory(mountPath, dirPath string) error {
+	dirPath = filepath.Clean(dirPath)
+	if fileFindMount(dirPath) >= 0 {
+		return errors.New("file directory is already mounted")
 	}
 
-	var paths []string
-	for p, a := range fileState.mountPaths {
-		if archive == a {
-			paths = append(paths, p)
+	mount := &fileMount{
+		sourcePath: dirPath,
+		paths:      make(map[string]string),
+	}
+
+	err := filepath.Walk(dirPath, func(sysPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dirPath, sysPath)
+		if err != nil {
+			return err
 		}
+
+		resourcePath := fileNormalizePath(mountPath + "/" + filepath.ToSlash(relPath))
+		mount.paths[resourcePath] = sysPath
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(mount.paths) == 0 {
+		return errors.New("file directory could not be mounted")
 	}
 
-	for _, p := range paths {
-		delete(fileState.mountPaths, p)
+	fileAddMount(mount)
+	return nil
+}
+
+func FileSetMountPriority(sourcePath string, priority int) error {
+	index := fileFindMount(sourcePath)
+	if index < 0 {
+		return errors.New("file source is not mounted")
 	}
 
+	mount := fileState.mounts[index]
+	fileState.mounts = append(fileState.mounts[:index], fileState.mounts[index+1:]...)
+	mount.priority = priority
+	fileAddMount(mount)
+
 	return nil
 }
 
+func FileUnmountArchive(archivePath string) error {
+	return fileRemoveMount(archivePath)
+}
+
+func FileUnmountDirectory(dirPath string) error {
+	return fileRemoveMount(filepath.Clean(dirPath))
+}
+
 func FileUnmountAll() error {
-	for _, archive := range fileState.mountPoints {
-		if err := archive.Close(); err != nil {
+	mounts := fileState.mounts
+	fileState.mounts = nil
+	fileState.dirs = nil
+
+	for _, mount := range mounts {
+		if err := mount.close(); err != nil {
 			return err
 		}
 	}
@@ -71,6 +187,63 @@ func FileUnmountAll() error {
 	return nil
 }
 
-func FileOpen(path string) (*File, error) {
-	return nil, nil
+func FileOpen(resourcePath string) (*File, error) {
+	mount, sourceResPath, ok := fileFindPath(fileNormalizePath(resourcePath))
+	if !ok {
+		return nil, ErrFileNotFound
+	}
+
+	handle, err := mount.open(sourceResPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{handle}, nil
+}
+
+func fileAddMount(mount *fileMount) {
+	fileState.dirs = nil
+	fileState.mounts = append(fileState.mounts, mount)
+	sort.SliceStable(fileState.mounts, func(i, j int) bool {
+		return fileState.mounts[i].priority < fileState.mounts[j].priority
+	})
+}
+
+func fileRemoveMount(sourcePath string) error {
+	index := fileFindMount(sourcePath)
+	if index < 0 {
+		return errors.New("file source is not mounted")
+	}
+
+	mount := fileState.mounts[index]
+	fileState.mounts = append(fileState.mounts[:index], fileState.mounts[index+1:]...)
+	fileState.dirs = nil
+
+	return mount.close()
+}
+
+func fileFindPath(resourcePath string) (*fileMount, string, bool) {
+	for i := len(fileState.mounts) - 1; i >= 0; i-- {
+		mount := fileState.mounts[i]
+		if sourceResPath, ok := mount.paths[resourcePath]; ok {
+			return mount, sourceResPath, true
+		}
+	}
+
+	return nil, "", false
+}
+
+func fileFindMount(sourcePath string) int {
+	for i, mount := range fileState.mounts {
+		if mount.sourcePath == sourcePath {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func fileNormalizePath(resourcePath string) string {
+	resourcePath = strings.ToLower(strings.Replace(resourcePath, "\\", "/", -1))
+	return strings.TrimPrefix(path.Clean("/"+resourcePath), "/")
 }
@@ -0,0 +1,155 @@
+package platform
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+
+	"github.com/FooSoft/lazarus/vfs"
+)
+
+type fileSystem struct{}
+
+func FileSystem() fs.FS {
+	return fileSystem{}
+}
+
+func (fileSystem) Open(name string) (fs.File, error) {
+	if !vfs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	resourcePath := fileSystemPath(name)
+	if entries, ok := fileSystemDirs()[resourcePath]; ok {
+		dirEntries, err := vfs.ReadDir(resourcePath, entries, fileSystemStat)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+
+		return vfs.NewDir(name, dirEntries), nil
+	}
+
+	file, err := FileOpen(resourcePath)
+	if err != nil {
+		if err == ErrFileNotFound {
+			err = fs.ErrNotExist
+		}
+
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	size, err := fileSize(file)
+	if err != nil {
+		file.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	return &fileSystemFile{file, vfs.NewFileInfo(name, size)}, nil
+}
+
+func (fileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !vfs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	resourcePath := fileSystemPath(name)
+	entries, ok := fileSystemDirs()[resourcePath]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	dirEntries, err := vfs.ReadDir(resourcePath, entries, fileSystemStat)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	return dirEntries, nil
+}
+
+func (fileSystem) Stat(name string) (fs.FileInfo, error) {
+	if !vfs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := fileSystemStat(fileSystemPath(name))
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	return info, nil
+}
+
+func (fileSystem) Glob(pattern string) ([]string, error) {
+	return vfs.Glob(fileSystemDirs(), strings.ToLower(pattern))
+}
+
+func fileSystemPath(name string) string {
+	if name == "." {
+		return name
+	}
+
+	return fileNormalizePath(name)
+}
+
+func fileSystemDirs() map[string][]string {
+	if fileState.dirs != nil {
+		return fileState.dirs
+	}
+
+	var (
+		resourcePaths []string
+		found         = make(map[string]bool)
+	)
+
+	for _, mount := range fileState.mounts {
+		for resourcePath := range mount.paths {
+			if !found[resourcePath] {
+				resourcePaths = append(resourcePaths, resourcePath)
+				found[resourcePath] = true
+			}
+		}
+	}
+
+	fileState.dirs = vfs.BuildDirs(resourcePaths)
+	return fileState.dirs
+}
+
+func fileSystemStat(resourcePath string) (fs.FileInfo, error) {
+	if _, ok := fileSystemDirs()[resourcePath]; ok {
+		return vfs.NewDirInfo(resourcePath), nil
+	}
+
+	mount, sourceResPath, ok := fileFindPath(resourcePath)
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	size, err := mount.size(sourceResPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return vfs.NewFileInfo(resourcePath, size), nil
+}
+
+func fileSize(file *File) (int64, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return size, nil
+}
+
+type fileSystemFile struct {
+	*File
+	info vfs.FileInfo
+}
+
+func (f *fileSystemFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
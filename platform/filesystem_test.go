@@ -0,0 +1,47 @@
+package platform
+
+import (
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "Data", "Global"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		filepath.Join(dir, "Data", "Global", "Items.txt"): "items",
+		filepath.Join(dir, "Data", "Plain.bin"):           "plain",
+	}
+
+	for path, data := range files {
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := FileMountDirectory("", dir); err != nil {
+		t.Fatal(err)
+	}
+	defer FileUnmountAll()
+
+	fsys := FileSystem()
+	if err := fstest.TestFS(fsys, "data/global/items.txt", "data/plain.bin"); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := fs.Glob(fsys, "DATA/*/Items.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 1 || matches[0] != "data/global/items.txt" {
+		t.Errorf("glob matches: %v", matches)
+	}
+}
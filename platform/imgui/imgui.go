@@ -55,6 +55,15 @@ func SliderInt(label string, value *int, min, max int) bool {
 	return result
 }
 
+func Checkbox(label string, value *bool) bool {
+	labelC := C.CString(label)
+	defer C.free(unsafe.Pointer(labelC))
+	valueC := C.bool(*value)
+	result := bool(C.igCheckbox(labelC, &valueC))
+	*value = bool(valueC)
+	return result
+}
+
 func Text(format string, args ...interface{}) {
 	label := fmt.Sprintf(format, args...)
 	labelStartC := C.CString(label)
@@ -66,6 +66,10 @@ func (r *BitReader) ReadUint(count int) uint64 {
 	return readBits(buffer, bitOffset, count)
 }
 
+func (r *BitReader) Offset() int {
+	return r.bitOffset
+}
+
 func (r *BitReader) Error() error {
 	return r.err
 }
@@ -0,0 +1,48 @@
+package streaming
+
+type BitWriter struct {
+	data      []byte
+	bitOffset int
+}
+
+func NewBitWriter() *BitWriter {
+	return new(BitWriter)
+}
+
+func (w *BitWriter) WriteBool(value bool) {
+	if value {
+		w.WriteUint(1, 1)
+	} else {
+		w.WriteUint(0, 1)
+	}
+}
+
+func (w *BitWriter) WriteInt(value int64, count int) {
+	w.WriteUint(uint64(value), count)
+}
+
+func (w *BitWriter) WriteUint(value uint64, count int) {
+	for i := 0; i < count; i++ {
+		if w.bitOffset%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+
+		if value&(1<<uint(i)) != 0 {
+			w.data[w.bitOffset/8] |= 1 << uint(w.bitOffset%8)
+		}
+
+		w.bitOffset++
+	}
+}
+
+func (w *BitWriter) Align() {
+	w.bitOffset = len(w.data) * 8
+}
+
+func (w *BitWriter) Offset() int {
+	return w.bitOffset
+}
+
+func (w *BitWriter) Bytes() []byte {
+	return w.data
+}
@@ -41,3 +41,35 @@ func TestBitReader(t *testing.T) {
 	test(2, (0xcdab>>13)&3)
 	test(9, 0xefcdab>>15)
 }
+
+func TestBitWriter(t *testing.T) {
+	w := NewBitWriter()
+	w.WriteUint(0x01, 8)
+	w.WriteUint(0x4523, 16)
+	w.WriteUint(0x67&0x07, 3)
+	w.WriteUint(0x8967>>3, 13)
+	w.WriteUint(0xcdab&0x1fff, 13)
+	w.WriteUint((0xcdab>>13)&3, 2)
+	w.WriteUint(0xefcdab>>15, 9)
+
+	if !bytes.Equal(w.Bytes(), data) {
+		t.Errorf("written data: %x, expected: %x", w.Bytes(), data)
+	}
+
+	w = NewBitWriter()
+	w.WriteInt(-3, 5)
+	w.WriteBool(true)
+	w.Align()
+	w.WriteUint(0xff, 8)
+
+	r := NewBitReader(bytes.NewReader(w.Bytes()))
+	if value := r.ReadInt(5); value != -3 {
+		t.Errorf("signed value: %d", value)
+	}
+	if !r.ReadBool() {
+		t.Error("bool value: false")
+	}
+	if r.ReadUint(2); r.ReadUint(8) != 0xff || w.Offset() != 16 {
+		t.Errorf("aligned data: %x", w.Bytes())
+	}
+}
@@ -1,17 +1,24 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	"image/png"
+	"io/ioutil"
+	gomath "math"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/FooSoft/lazarus/formats/dat"
 	"github.com/FooSoft/lazarus/formats/dc6"
+	"github.com/FooSoft/lazarus/graphics/sprite"
+	"github.com/FooSoft/lazarus/math"
 )
 
 func loadPalette(path string) (*dat.Palette, error) {
@@ -32,45 +39,304 @@ func loadSprite(path string) (*dc6.Animation, error) {
 	return dc6.NewFromReader(fp)
 }
 
-func extractSprite(spritePath string, palette *dat.Palette, targetDir string) error {
-	sprite, err := loadSprite(spritePath)
+func exportSprite(spritePath string, palette *dat.Palette, targetDir, mode string, frameRate int) error {
+	source, err := loadSprite(spritePath)
 	if err != nil {
 		return err
 	}
 
-	for di, direction := range sprite.Directions {
-		for fi, frame := range direction.Frames {
-			img := image.NewRGBA(image.Rect(0, 0, frame.Size.X, frame.Size.Y))
-			for y := 0; y < frame.Size.Y; y++ {
-				for x := 0; x < frame.Size.X; x++ {
-					colorSrc := palette.Colors[frame.Data[y*frame.Size.X+x]]
-					colorDst := color.RGBA{colorSrc.R, colorSrc.G, colorSrc.B, 0xff}
-					img.Set(x, y, colorDst)
-				}
+	target := new(sprite.Sprite)
+	for _, directionSrc := range source.Directions {
+		var directionDst sprite.Direction
+		for _, frame := range directionSrc.Frames {
+			directionDst.Frames = append(directionDst.Frames, sprite.NewFrame(frame.Size, frame.Offset, frame.Data))
+		}
+
+		target.Directions = append(target.Directions, directionDst)
+	}
+
+	return target.Export(filepath.Join(targetDir, filepath.Base(spritePath)), palette, mode, frameRate)
+}
+
+type importOptions struct {
+	directions int
+	frames     int
+	grid       math.Vec2i
+	offset     math.Vec2i
+	offsets    []math.Vec2i
+	dither     bool
+}
+
+func importSprite(spritePath string, imagePaths []string, palette *dat.Palette, options importOptions) error {
+	imagePaths, err := expandImagePaths(imagePaths)
+	if err != nil {
+		return err
+	}
+
+	var images []image.Image
+	for _, imagePath := range imagePaths {
+		img, err := loadImage(imagePath)
+		if err != nil {
+			return err
+		}
+
+		if options.grid.X <= 0 || options.grid.Y <= 0 {
+			images = append(images, img)
+			continue
+		}
+
+		bounds := img.Bounds()
+		if bounds.Dx()%options.grid.X != 0 || bounds.Dy()%options.grid.Y != 0 {
+			return fmt.Errorf("%s: image size is not a multiple of the frame grid", imagePath)
+		}
+
+		subImager, ok := img.(interface {
+			SubImage(r image.Rectangle) image.Image
+		})
+
+		if !ok {
+			return fmt.Errorf("%s: unsupported image type", imagePath)
+		}
+
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += options.grid.Y {
+			for x := bounds.Min.X; x < bounds.Max.X; x += options.grid.X {
+				images = append(images, subImager.SubImage(image.Rect(x, y, x+options.grid.X, y+options.grid.Y)))
+			}
+		}
+	}
+
+	if options.directions <= 0 {
+		return errors.New("direction count must be positive")
+	}
+
+	frames := options.frames
+	if frames <= 0 {
+		frames = len(images) / options.directions
+	}
+
+	if frames == 0 || frames*options.directions != len(images) {
+		return fmt.Errorf("%d frames cannot be split into %d directions", len(images), options.directions)
+	}
+
+	if len(options.offsets) > 0 && len(options.offsets) != len(images) {
+		return fmt.Errorf("%d frame offsets given for %d frames", len(options.offsets), len(images))
+	}
+
+	sprite := &dc6.Animation{Directions: make([]dc6.Direction, options.directions)}
+	for i, img := range images {
+		bounds := img.Bounds()
+		frame := dc6.Frame{
+			Size:   math.Vec2i{X: bounds.Dx(), Y: bounds.Dy()},
+			Offset: options.offset,
+			Data:   quantizeImage(img, palette, options.dither),
+		}
+
+		if len(options.offsets) > 0 {
+			frame.Offset = options.offsets[i]
+		}
+
+		direction := &sprite.Directions[i/frames]
+		direction.Frames = append(direction.Frames, frame)
+	}
+
+	fp, err := os.Create(spritePath)
+	if err != nil {
+		return err
+	}
+
+	if err := sprite.Write(fp); err != nil {
+		fp.Close()
+		return err
+	}
+
+	return fp.Close()
+}
+
+func expandImagePaths(paths []string) ([]string, error) {
+	var result []string
+	for _, imagePath := range paths {
+		info, err := os.Stat(imagePath)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			result = append(result, imagePath)
+			continue
+		}
+
+		entries, err := os.ReadDir(imagePath)
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ".png") {
+				names = append(names, entry.Name())
+			}
+		}
+
+		if len(names) == 0 {
+			return nil, fmt.Errorf("%s: directory contains no png files", imagePath)
+		}
+
+		sort.Strings(names)
+		for _, name := range names {
+			result = append(result, filepath.Join(imagePath, name))
+		}
+	}
+
+	return result, nil
+}
+
+func loadOffsets(path string) ([]math.Vec2i, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var offsets []math.Vec2i
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+
+		offset, err := parseVec2i(line, ",")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+
+		offsets = append(offsets, offset)
+	}
+
+	return offsets, nil
+}
+
+func loadImage(path string) (image.Image, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return png.Decode(fp)
+}
+
+func quantizeImage(img image.Image, palette *dat.Palette, dither bool) []byte {
+	var (
+		bounds   = img.Bounds()
+		width    = bounds.Dx()
+		height   = bounds.Dy()
+		data     = make([]byte, width*height)
+		errCurr  = make([][3]float64, width+2)
+		errNext  = make([][3]float64, width+2)
+		indexMap = make(map[color.NRGBA]byte)
+	)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			colorSrc := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			if colorSrc.A < 0x80 {
+				continue
 			}
 
-			basePath := filepath.Base(spritePath)
-			targetPath := fmt.Sprintf("%s_%d_%d.png", filepath.Join(targetDir, basePath), di, fi)
+			if !dither {
+				index, ok := indexMap[colorSrc]
+				if !ok {
+					index = nearestColor(palette, float64(colorSrc.R), float64(colorSrc.G), float64(colorSrc.B))
+					indexMap[colorSrc] = index
+				}
 
-			fp, err := os.Create(targetPath)
-			if err != nil {
-				return err
+				data[y*width+x] = index
+				continue
 			}
-			defer fp.Close()
 
-			if err := png.Encode(fp, img); err != nil {
-				return err
+			var value [3]float64
+			for i, channel := range []byte{colorSrc.R, colorSrc.G, colorSrc.B} {
+				value[i] = float64(channel) + errCurr[x+1][i]
+				if value[i] < 0 {
+					value[i] = 0
+				} else if value[i] > 0xff {
+					value[i] = 0xff
+				}
+			}
+
+			index := nearestColor(palette, value[0], value[1], value[2])
+			data[y*width+x] = index
+
+			colorDst := palette.Colors[index]
+			for i, channel := range []byte{colorDst.R, colorDst.G, colorDst.B} {
+				diff := value[i] - float64(channel)
+				errCurr[x+2][i] += diff * 7 / 16
+				errNext[x][i] += diff * 3 / 16
+				errNext[x+1][i] += diff * 5 / 16
+				errNext[x+2][i] += diff * 1 / 16
 			}
 		}
+
+		errCurr, errNext = errNext, errCurr
+		for i := range errNext {
+			errNext[i] = [3]float64{}
+		}
+	}
+
+	return data
+}
+
+func nearestColor(palette *dat.Palette, r, g, b float64) byte {
+	var (
+		bestIndex    = 1
+		bestDistance = gomath.MaxFloat64
+	)
+
+	for i := 1; i < len(palette.Colors); i++ {
+		var (
+			colorPal = palette.Colors[i]
+			dr       = r - float64(colorPal.R)
+			dg       = g - float64(colorPal.G)
+			db       = b - float64(colorPal.B)
+			distance = dr*dr + dg*dg + db*db
+		)
+
+		if distance < bestDistance {
+			bestIndex = i
+			bestDistance = distance
+		}
+	}
+
+	return byte(bestIndex)
+}
+
+func parseVec2i(value, separator string) (math.Vec2i, error) {
+	var vec math.Vec2i
+	if len(value) == 0 {
+		return vec, nil
+	}
+
+	if _, err := fmt.Sscanf(strings.Replace(value, separator, " ", 1), "%d %d", &vec.X, &vec.Y); err != nil {
+		return vec, fmt.Errorf("invalid value %q", value)
 	}
 
-	return nil
+	return vec, nil
 }
 
 func main() {
-	targetDir := flag.String("target", ".", "target directory")
+	var (
+		targetDir  = flag.String("target", ".", "target directory")
+		exportMode = flag.String("export", "frames", "export mode (frames, sheet, gif, apng)")
+		frameRate  = flag.Int("fps", 15, "frame rate of exported animations")
+		importMode = flag.Bool("import", false, "import png frames into a dc6 file")
+		directions = flag.Int("directions", 1, "number of directions to import")
+		frames     = flag.Int("frames", 0, "number of frames per direction to import (automatic if zero)")
+		grid       = flag.String("grid", "", "frame size used to split imported sprite sheets (WxH)")
+		offset     = flag.String("offset", "", "frame offset of imported frames (X,Y)")
+		offsets    = flag.String("offsets", "", "file with one frame offset (X,Y) per line for imported frames")
+		dither     = flag.Bool("dither", false, "dither imported frames")
+	)
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] palette_file [dc6_files]\n", path.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "       %s -import [options] palette_file dc6_file [png_files_or_dirs]\n", path.Base(os.Args[0]))
 		fmt.Fprintf(os.Stderr, "Parameters:\n\n")
 		flag.PrintDefaults()
 	}
@@ -88,8 +354,35 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *importMode {
+		if flag.NArg() < 3 {
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		options := importOptions{directions: *directions, frames: *frames, dither: *dither}
+		if options.grid, err = parseVec2i(*grid, "x"); err == nil {
+			options.offset, err = parseVec2i(*offset, ",")
+		}
+
+		if err == nil && len(*offsets) > 0 {
+			options.offsets, err = loadOffsets(*offsets)
+		}
+
+		if err == nil {
+			err = importSprite(flag.Arg(1), flag.Args()[2:], palette, options)
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	for i := 1; i < flag.NArg(); i++ {
-		if err := extractSprite(flag.Arg(1), palette, *targetDir); err != nil {
+		if err := exportSprite(flag.Arg(i), palette, *targetDir, *exportMode, *frameRate); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/FooSoft/lazarus/formats/dat"
+	"github.com/FooSoft/lazarus/math"
+)
+
+func writeImage(t *testing.T, path string, gray byte, size math.Vec2i) {
+	img := image.NewNRGBA(image.Rect(0, 0, size.X, size.Y))
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{gray, gray, gray, 0xff})
+		}
+	}
+
+	img.SetNRGBA(0, 0, color.NRGBA{})
+
+	var buff bytes.Buffer
+	if err := png.Encode(&buff, img); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, buff.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportSprite(t *testing.T) {
+	var (
+		dir        = t.TempDir()
+		frameDir   = filepath.Join(dir, "frames")
+		sheetPath  = filepath.Join(dir, "sheet.png")
+		spritePath = filepath.Join(dir, "sprite.dc6")
+	)
+
+	if err := os.Mkdir(frameDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	writeImage(t, filepath.Join(frameDir, "frame_02.png"), 0x80, math.Vec2i{X: 2, Y: 3})
+	writeImage(t, filepath.Join(frameDir, "frame_01.png"), 0x40, math.Vec2i{X: 3, Y: 2})
+	writeImage(t, sheetPath, 0xc0, math.Vec2i{X: 1, Y: 1})
+
+	if err := ioutil.WriteFile(filepath.Join(frameDir, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := importOptions{
+		directions: 1,
+		offsets:    []math.Vec2i{{X: -1, Y: 2}, {X: 3, Y: -4}, {X: 0, Y: 0}},
+	}
+
+	if err := importSprite(spritePath, []string{frameDir, sheetPath}, dat.NewFromGrayscale(), options); err != nil {
+		t.Fatal(err)
+	}
+
+	sprite, err := loadSprite(spritePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sprite.Directions) != 1 || len(sprite.Directions[0].Frames) != 3 {
+		t.Fatalf("sprite: %+v", sprite)
+	}
+
+	expected := []struct {
+		size   math.Vec2i
+		offset math.Vec2i
+		data   []byte
+	}{
+		{math.Vec2i{X: 3, Y: 2}, math.Vec2i{X: -1, Y: 2}, []byte{0, 0x40, 0x40, 0x40, 0x40, 0x40}},
+		{math.Vec2i{X: 2, Y: 3}, math.Vec2i{X: 3, Y: -4}, []byte{0, 0x80, 0x80, 0x80, 0x80, 0x80}},
+		{math.Vec2i{X: 1, Y: 1}, math.Vec2i{}, []byte{0}},
+	}
+
+	for i, frame := range sprite.Directions[0].Frames {
+		if frame.Size != expected[i].size || frame.Offset != expected[i].offset || !bytes.Equal(frame.Data, expected[i].data) {
+			t.Errorf("frame %d: %+v, expected: %+v", i, frame, expected[i])
+		}
+	}
+
+	options.offsets = options.offsets[:2]
+	if err := importSprite(spritePath, []string{frameDir, sheetPath}, dat.NewFromGrayscale(), options); err == nil {
+		t.Error("mismatched frame offsets were accepted")
+	}
+}
+
+func TestLoadOffsets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "offsets.txt")
+	if err := ioutil.WriteFile(path, []byte("-32,0\r\n\r\n4,-5\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	offsets, err := loadOffsets(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(offsets) != 2 || offsets[0] != (math.Vec2i{X: -32}) || offsets[1] != (math.Vec2i{X: 4, Y: -5}) {
+		t.Errorf("offsets: %+v", offsets)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("1;2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadOffsets(path); err == nil {
+		t.Error("invalid offset was accepted")
+	}
+}
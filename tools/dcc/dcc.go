@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 
 	"github.com/FooSoft/lazarus/formats/dat"
 	"github.com/FooSoft/lazarus/formats/dcc"
+	"github.com/FooSoft/lazarus/graphics/sprite"
 )
 
 func loadPalette(path string) (*dat.Palette, error) {
@@ -28,17 +30,32 @@ func loadSprite(path string) (*dcc.Sprite, error) {
 	return dcc.NewFromReader(fp)
 }
 
-func extractSprite(spritePath string, palette *dat.Palette, targetDir string) error {
-	_, err := loadSprite(spritePath)
+func exportSprite(spritePath string, palette *dat.Palette, targetDir, mode string, frameRate int) error {
+	source, err := loadSprite(spritePath)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	target := new(sprite.Sprite)
+	for _, directionSrc := range source.Directions {
+		var directionDst sprite.Direction
+		for _, frame := range directionSrc.Frames {
+			directionDst.Frames = append(directionDst.Frames, sprite.NewFrame(frame.Size, frame.Offset, frame.Data))
+		}
+
+		target.Directions = append(target.Directions, directionDst)
+	}
+
+	return target.Export(filepath.Join(targetDir, filepath.Base(spritePath)), palette, mode, frameRate)
 }
 
 func main() {
-	targetDir := flag.String("target", ".", "target directory")
+	var (
+		targetDir  = flag.String("target", ".", "target directory")
+		exportMode = flag.String("export", "frames", "export mode (frames, sheet, gif, apng)")
+		frameRate  = flag.Int("fps", 15, "frame rate of exported animations")
+	)
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options] palette_file [dcc_files]\n", path.Base(os.Args[0]))
 		fmt.Fprintf(os.Stderr, "Parameters:\n\n")
@@ -59,7 +76,7 @@ func main() {
 	}
 
 	for i := 1; i < flag.NArg(); i++ {
-		if err := extractSprite(flag.Arg(1), palette, *targetDir); err != nil {
+		if err := exportSprite(flag.Arg(i), palette, *targetDir, *exportMode, *frameRate); err != nil {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
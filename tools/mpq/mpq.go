@@ -1,87 +1,431 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/FooSoft/lazarus/formats/mpq"
 	"github.com/bmatcuk/doublestar"
 )
 
-func list(mpqPath, filter string) error {
+func openArchive(mpqPath string, listFiles []string) (*mpq.Archive, error) {
 	arch, err := mpq.NewFromFile(mpqPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, listFile := range listFiles {
+		file, err := os.Open(listFile)
+		if err != nil {
+			arch.Close()
+			return nil, err
+		}
+
+		err = arch.AddListFile(file)
+		file.Close()
+		if err != nil {
+			arch.Close()
+			return nil, err
+		}
+	}
+
+	return arch, nil
+}
+
+func list(mpqPath, filter string, listFiles []string, hashes bool) error {
+	arch, err := openArchive(mpqPath, listFiles)
 	if err != nil {
 		return err
 	}
 	defer arch.Close()
 
-	for _, resPath := range arch.GetPaths() {
-		match, err := doublestar.Match(filter, resPath)
+	for _, entry := range arch.Entries() {
+		match, err := doublestar.Match(filter, entry.Path)
 		if err != nil {
 			return err
 		}
 
-		if match {
-			fmt.Println(resPath)
+		if !match {
+			continue
+		}
+
+		if hashes {
+			fmt.Printf("%s\t%08x\t%08x\t%d\n", entry.Path, entry.NameA, entry.NameB, entry.Locale)
+		} else {
+			fmt.Println(entry.Path)
 		}
 	}
 
 	return nil
 }
 
-func extract(mpqPath, filter, targetDir string) error {
-	arch, err := mpq.NewFromFile(mpqPath)
+func extract(mpqPath, filter, targetDir string, listFiles []string, jobs int) error {
+	arch, err := openArchive(mpqPath, listFiles)
 	if err != nil {
 		return err
 	}
 	defer arch.Close()
 
+	var resPaths []string
 	for _, resPath := range arch.GetPaths() {
 		match, err := doublestar.Match(filter, resPath)
 		if err != nil {
 			return err
 		}
 
-		if !match {
-			continue
+		if match {
+			resPaths = append(resPaths, resPath)
 		}
+	}
 
-		fmt.Println(resPath)
+	sort.Strings(resPaths)
+
+	var (
+		queue    = make(chan string)
+		failures []string
+		skipped  int
+		done     int
+		bytes    int64
+		mutex    sync.Mutex
+		wg       sync.WaitGroup
+	)
+
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for resPath := range queue {
+				size, skip, err := extractFile(arch, resPath, targetDir)
+
+				mutex.Lock()
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("%s: %v", resPath, err))
+				}
+				if skip {
+					skipped++
+				}
+				done++
+				bytes += size
+				fmt.Fprintf(os.Stderr, "\r%s: %d/%d files, %d skipped, %d bytes", mpqPath, done, len(resPaths), skipped, bytes)
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, resPath := range resPaths {
+		queue <- resPath
+	}
+
+	close(queue)
+	wg.Wait()
+
+	if len(resPaths) > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		for _, failure := range failures {
+			fmt.Fprintln(os.Stderr, failure)
+		}
+
+		return fmt.Errorf("%s: %d of %d files failed to extract", mpqPath, len(failures), len(resPaths))
+	}
 
-		resFile, err := arch.OpenFile(resPath)
+	return nil
+}
+
+func extractFile(arch *mpq.Archive, resPath, targetDir string) (int64, bool, error) {
+	sysPath, err := targetPath(targetDir, resPath)
+	if err != nil {
+		return 0, false, err
+	}
+
+	resFile, err := arch.OpenFile(resPath)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resFile.Close()
+
+	if info, err := os.Stat(sysPath); err == nil && info.Size() == resFile.Size() {
+		match, err := compareFile(resFile, sysPath)
 		if err != nil {
-			return err
+			return 0, false, err
+		}
+
+		if match {
+			return resFile.Size(), true, nil
+		}
+
+		if _, err := resFile.Seek(0, io.SeekStart); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sysPath), 0777); err != nil {
+		return 0, false, err
+	}
+
+	sysFile, err := os.Create(sysPath)
+	if err != nil {
+		return 0, false, err
+	}
+
+	size, err := io.Copy(sysFile, resFile)
+	if closeErr := sysFile.Close(); err == nil {
+		err = closeErr
+	}
+
+	return size, false, err
+}
+
+func targetPath(targetDir, resPath string) (string, error) {
+	sysPath := filepath.Join(targetDir, resPath)
+	relPath, err := filepath.Rel(targetDir, sysPath)
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.IsAbs(relPath) || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", errors.New("file path escapes target directory")
+	}
+
+	return sysPath, nil
+}
+
+func compareFile(resFile *mpq.File, sysPath string) (bool, error) {
+	sysFile, err := os.Open(sysPath)
+	if err != nil {
+		return false, err
+	}
+	defer sysFile.Close()
+
+	var (
+		resHash = crc32.NewIEEE()
+		sysHash = crc32.NewIEEE()
+	)
+
+	if _, err := io.Copy(resHash, resFile); err != nil {
+		return false, err
+	}
+
+	if _, err := io.Copy(sysHash, sysFile); err != nil {
+		return false, err
+	}
+
+	return resHash.Sum32() == sysHash.Sum32(), nil
+}
+
+func verify(mpqPath string, listFiles []string, jsonOutput bool) (bool, error) {
+	arch, err := openArchive(mpqPath, listFiles)
+	if err != nil {
+		return false, err
+	}
+	defer arch.Close()
+
+	report, err := arch.Verify()
+	if err != nil {
+		return false, err
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(struct {
+			Archive string `json:"archive"`
+			*mpq.VerifyReport
+		}{mpqPath, report}); err != nil {
+			return false, err
+		}
+	} else {
+		for _, issue := range report.Issues {
+			fmt.Printf("%s: %s\n", mpqPath, issue)
+		}
+
+		fmt.Printf("%s: verified %d of %d blocks, %d issues\n", mpqPath, report.VerifiedFiles, report.Blocks, len(report.Issues))
+	}
+
+	return report.Ok(), nil
+}
+
+func create(mpqPath, sourceDir string, options mpq.FileOptions, hashSize int) error {
+	sysPaths, err := collect(sourceDir)
+	if err != nil {
+		return err
+	}
+
+	return build(mpqPath, nil, nil, sysPaths, options, hashSize)
+}
+
+func add(mpqPath, sourceDir string, listFiles []string, options mpq.FileOptions, hashSize int) error {
+	sysPaths, err := collect(sourceDir)
+	if err != nil {
+		return err
+	}
+
+	arch, err := openArchive(mpqPath, listFiles)
+	if err != nil {
+		return err
+	}
+	defer arch.Close()
+
+	var resPaths []string
+	for _, resPath := range arch.GetPaths() {
+		if _, ok := sysPaths[resPath]; !ok {
+			resPaths = append(resPaths, resPath)
+		}
+	}
+
+	return build(mpqPath, arch, resPaths, sysPaths, options, hashSize)
+}
+
+func remove(mpqPath string, filters, listFiles []string, hashSize int) error {
+	arch, err := openArchive(mpqPath, listFiles)
+	if err != nil {
+		return err
+	}
+	defer arch.Close()
+
+	var resPaths []string
+	for _, resPath := range arch.GetPaths() {
+		var match bool
+		for _, filter := range filters {
+			if match, err = doublestar.Match(filter, resPath); err != nil {
+				return err
+			}
+
+			if match {
+				fmt.Println(resPath)
+				break
+			}
 		}
-		defer resFile.Close()
 
-		sysPath := filepath.Join(targetDir, resPath)
-		if err := os.MkdirAll(filepath.Dir(sysPath), 0777); err != nil {
+		if !match {
+			resPaths = append(resPaths, resPath)
+		}
+	}
+
+	return build(mpqPath, arch, resPaths, nil, mpq.FileOptions{}, hashSize)
+}
+
+func compact(mpqPath string, listFiles []string, hashSize int) error {
+	arch, err := openArchive(mpqPath, listFiles)
+	if err != nil {
+		return err
+	}
+	defer arch.Close()
+
+	return build(mpqPath, arch, arch.GetPaths(), nil, mpq.FileOptions{}, hashSize)
+}
+
+func collect(sourceDir string) (map[string]string, error) {
+	sysPaths := make(map[string]string)
+	err := filepath.Walk(sourceDir, func(sysPath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
 			return err
 		}
 
-		sysFile, err := os.Create(sysPath)
+		resPath, err := filepath.Rel(sourceDir, sysPath)
 		if err != nil {
 			return err
 		}
 
-		if _, err := io.Copy(sysFile, resFile); err != nil {
-			sysFile.Close()
+		sysPaths[strings.ToLower(resPath)] = sysPath
+		return nil
+	})
+
+	return sysPaths, err
+}
+
+func build(mpqPath string, arch *mpq.Archive, resPaths []string, sysPaths map[string]string, options mpq.FileOptions, hashSize int) error {
+	if hashSize == 0 {
+		hashSize = 16
+		for hashSize < (len(resPaths)+len(sysPaths)+2)*2 {
+			hashSize <<= 1
+		}
+
+		// unnamed files can only keep their hash table slots in a table of the same size
+		if arch != nil && arch.HashTableSize() >= hashSize {
+			hashSize = arch.HashTableSize()
+		}
+	}
+
+	tempPath := mpqPath + ".tmp"
+	writer, err := mpq.NewWriter(tempPath, hashSize)
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(resPaths)
+	for _, resPath := range resPaths {
+		// special files are regenerated by the writer
+		if strings.HasPrefix(resPath, "(") {
+			continue
+		}
+
+		if err := writer.CopyFile(arch, resPath); err != nil {
+			writer.Close()
+			os.Remove(tempPath)
+			return fmt.Errorf("%s: %v", resPath, err)
+		}
+	}
+
+	var sysResPaths []string
+	for resPath := range sysPaths {
+		sysResPaths = append(sysResPaths, resPath)
+	}
+
+	sort.Strings(sysResPaths)
+	for _, resPath := range sysResPaths {
+		fmt.Println(resPath)
+
+		data, err := ioutil.ReadFile(sysPaths[resPath])
+		if err == nil {
+			err = writer.AddFile(resPath, data, options)
+		}
+
+		if err != nil {
+			writer.Close()
+			os.Remove(tempPath)
 			return err
 		}
+	}
 
-		sysFile.Close()
+	if err := writer.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
 	}
 
-	return nil
+	if arch != nil {
+		arch.Close()
+	}
+
+	return os.Rename(tempPath, mpqPath)
 }
 
 func main() {
 	var (
-		filter    = flag.String("filter", "**", "wildcard file filter")
-		targetDir = flag.String("target", ".", "target directory")
+		filter      = flag.String("filter", "**", "wildcard file filter")
+		targetDir   = flag.String("target", ".", "target directory")
+		compression = flag.String("compression", "implode", "compression type (none, implode, zlib, bzip2)")
+		encrypt     = flag.Bool("encrypt", false, "encrypt added files")
+		hashSize    = flag.Int("hashsize", 0, "hash table size (automatic if zero)")
+		jsonOutput  = flag.Bool("json", false, "output verification results as json")
+		jobs        = flag.Int("jobs", runtime.NumCPU(), "number of files to extract in parallel")
+		listFile    = flag.String("listfile", "", "external listfiles used to resolve unnamed files")
+		hashes      = flag.Bool("hashes", false, "list name hashes and locales")
 	)
 
 	flag.Usage = func() {
@@ -97,19 +441,102 @@ func main() {
 		os.Exit(2)
 	}
 
+	compressions := map[string]mpq.Compression{
+		"none":    mpq.CompressNone,
+		"implode": mpq.CompressImplode,
+		"zlib":    mpq.CompressZlib,
+		"bzip2":   mpq.CompressBzip2,
+	}
+
+	listFiles := filepath.SplitList(*listFile)
+	if *jobs < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	options := mpq.FileOptions{Encrypt: *encrypt}
+	if compression, ok := compressions[*compression]; ok {
+		options.Compression = compression
+	} else {
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	switch flag.Arg(0) {
 	case "list":
+		exitCode := 0
 		for i := 1; i < flag.NArg(); i++ {
-			if err := list(flag.Arg(i), *filter); err != nil {
+			if err := list(flag.Arg(i), *filter, listFiles, *hashes); err != nil {
 				fmt.Fprintln(os.Stderr, err)
+				exitCode = 1
 			}
 		}
+
+		os.Exit(exitCode)
 	case "extract":
+		exitCode := 0
+		for i := 1; i < flag.NArg(); i++ {
+			if err := extract(flag.Arg(i), *filter, *targetDir, listFiles, *jobs); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exitCode = 1
+			}
+		}
+
+		os.Exit(exitCode)
+	case "verify":
+		exitCode := 0
+		for i := 1; i < flag.NArg(); i++ {
+			ok, err := verify(flag.Arg(i), listFiles, *jsonOutput)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+
+			if err != nil || !ok {
+				exitCode = 1
+			}
+		}
+
+		os.Exit(exitCode)
+	case "create":
+		if flag.NArg() != 3 {
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		if err := create(flag.Arg(1), flag.Arg(2), options, *hashSize); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "add":
+		if flag.NArg() != 3 {
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		if err := add(flag.Arg(1), flag.Arg(2), listFiles, options, *hashSize); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "remove":
+		if flag.NArg() < 3 {
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		if err := remove(flag.Arg(1), flag.Args()[2:], listFiles, *hashSize); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	case "compact":
+		exitCode := 0
 		for i := 1; i < flag.NArg(); i++ {
-			if err := extract(flag.Arg(i), *filter, *targetDir); err != nil {
+			if err := compact(flag.Arg(i), listFiles, *hashSize); err != nil {
 				fmt.Fprintln(os.Stderr, err)
+				exitCode = 1
 			}
 		}
+
+		os.Exit(exitCode)
 	default:
 		flag.Usage()
 		os.Exit(2)
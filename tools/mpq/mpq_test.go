@@ -0,0 +1,28 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestTargetPath(t *testing.T) {
+	targetDir := filepath.Join("extract", "target")
+
+	for resPath, valid := range map[string]bool{
+		filepath.Join("data", "global", "items.txt"):   true,
+		filepath.Join("data", "..", "items.txt"):       true,
+		"..data":                                       true,
+		filepath.Join("..", "..", "items.txt"):         false,
+		filepath.Join("data", "..", "..", "items.txt"): false,
+		"..": false,
+	} {
+		sysPath, err := targetPath(targetDir, resPath)
+		if valid && (err != nil || sysPath != filepath.Join(targetDir, resPath)) {
+			t.Errorf("%s: %s (%v)", resPath, sysPath, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("%s: escaping path was accepted", resPath)
+		}
+	}
+}
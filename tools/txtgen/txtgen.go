@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/FooSoft/lazarus/formats/txt"
+	"github.com/FooSoft/lazarus/platform"
+)
+
+const tableDir = "data/global/excel"
+
+var knownTables = []struct {
+	name     string
+	typeName string
+}{
+	{"armor", "Armor"},
+	{"charstats", "CharStats"},
+	{"cubemain", "CubeMain"},
+	{"difficultylevels", "DifficultyLevels"},
+	{"experience", "Experience"},
+	{"gems", "Gems"},
+	{"hireling", "Hireling"},
+	{"inventory", "Inventory"},
+	{"itemstatcost", "ItemStatCost"},
+	{"itemtypes", "ItemTypes"},
+	{"levels", "Levels"},
+	{"lvlprest", "LvlPrest"},
+	{"lvltypes", "LvlTypes"},
+	{"magicprefix", "MagicPrefix"},
+	{"magicsuffix", "MagicSuffix"},
+	{"misc", "Misc"},
+	{"missiles", "Missiles"},
+	{"monstats", "MonStats"},
+	{"monstats2", "MonStats2"},
+	{"objects", "Objects"},
+	{"properties", "Properties"},
+	{"runes", "Runes"},
+	{"setitems", "SetItems"},
+	{"sets", "Sets"},
+	{"skilldesc", "SkillDesc"},
+	{"skills", "Skills"},
+	{"states", "States"},
+	{"superuniques", "SuperUniques"},
+	{"treasureclassex", "TreasureClassEx"},
+	{"uniqueitems", "UniqueItems"},
+	{"weapons", "Weapons"},
+}
+
+func mountSource(sourcePath string) error {
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		return platform.FileMountDirectory("/", sourcePath)
+	}
+
+	return platform.FileMountArchive("/", sourcePath)
+}
+
+func fieldName(column string) string {
+	var name []rune
+	upper := true
+	for _, char := range column {
+		if !unicode.IsLetter(char) && !unicode.IsDigit(char) {
+			upper = true
+			continue
+		}
+
+		if upper {
+			char = unicode.ToUpper(char)
+			upper = false
+		}
+
+		name = append(name, char)
+	}
+
+	if len(name) == 0 || unicode.IsDigit(name[0]) {
+		name = append([]rune("Column"), name...)
+	}
+
+	return string(name)
+}
+
+func fieldType(table *txt.Table, column int) string {
+	var hasValues bool
+	for _, row := range table.Rows {
+		value := strings.TrimSpace(row[column])
+		if len(value) == 0 {
+			continue
+		}
+
+		if _, err := strconv.ParseInt(value, 10, 32); err != nil {
+			return "string"
+		}
+
+		hasValues = true
+	}
+
+	if hasValues {
+		return "int"
+	}
+
+	return "string"
+}
+
+func writeTable(buff *bytes.Buffer, table *txt.Table, name, typeName string) {
+	var (
+		names   = make(map[string]bool)
+		columns = make(map[string]bool)
+	)
+
+	fmt.Fprintf(buff, "type %s struct {\n", typeName)
+	for i, column := range table.Columns {
+		if len(strings.TrimSpace(column)) == 0 || columns[column] {
+			continue
+		}
+
+		columns[column] = true
+
+		field := fieldName(column)
+		for suffix := 2; names[field]; suffix++ {
+			field = fmt.Sprintf("%s%d", fieldName(column), suffix)
+		}
+
+		names[field] = true
+		fmt.Fprintf(buff, "\t%s %s `txt:%q`\n", field, fieldType(table, i), column)
+	}
+	fmt.Fprintf(buff, "}\n\n")
+
+	fmt.Fprintf(buff, "func Load%s(fsys fs.FS) ([]%s, error) {\n", typeName, typeName)
+	fmt.Fprintf(buff, "\tvar records []%s\n", typeName)
+	fmt.Fprintf(buff, "\terr := txt.Load(fsys, %q, &records)\n", path.Join(tableDir, name+".txt"))
+	fmt.Fprintf(buff, "\treturn records, err\n")
+	fmt.Fprintf(buff, "}\n\n")
+}
+
+func generate(outputPath, packageName string) error {
+	var buff bytes.Buffer
+	fmt.Fprintf(&buff, "// Code generated by txtgen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buff, "package %s\n\n", packageName)
+	fmt.Fprintf(&buff, "import (\n\t\"io/fs\"\n\n\t\"github.com/FooSoft/lazarus/formats/txt\"\n)\n\n")
+
+	for _, known := range knownTables {
+		tablePath := path.Join(tableDir, known.name+".txt")
+		table, err := txt.Open(platform.FileSystem(), tablePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", tablePath, err)
+			continue
+		}
+
+		writeTable(&buff, table, known.name, known.typeName)
+	}
+
+	source, err := format.Source(buff.Bytes())
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(outputPath, source, 0644)
+}
+
+func main() {
+	packageName := flag.String("package", "tables", "package name of generated code")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] output_file [mpq_files_or_dirs]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(os.Stderr, "Parameters:\n\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	for i := 1; i < flag.NArg(); i++ {
+		if err := mountSource(flag.Arg(i)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	defer platform.FileUnmountAll()
+
+	if err := generate(flag.Arg(0), *packageName); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
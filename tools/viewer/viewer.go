@@ -5,10 +5,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/FooSoft/lazarus/formats/animdata"
 	"github.com/FooSoft/lazarus/formats/dat"
 	"github.com/FooSoft/lazarus/formats/dc6"
+	"github.com/FooSoft/lazarus/formats/palshift"
+	"github.com/FooSoft/lazarus/formats/pl2"
+	"github.com/FooSoft/lazarus/formats/tbl"
 	"github.com/FooSoft/lazarus/graphics"
 	"github.com/FooSoft/lazarus/math"
 	"github.com/FooSoft/lazarus/platform"
@@ -24,6 +29,37 @@ func loadPalette(path string) (*dat.Palette, error) {
 	return dat.NewFromReader(fp)
 }
 
+func loadTables(path string) (*pl2.Tables, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return pl2.NewFromReader(fp)
+}
+
+func loadColormap(path string) (*palshift.Colormap, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return palshift.NewFromReader(fp)
+}
+
+func loadAnimData(path string) (*animdata.AnimData, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+	return animdata.NewFromReader(fp)
+}
+
+func loadStrings(path string) (*tbl.Strings, error) {
+	return tbl.LoadDir(os.DirFS(path), ".")
+}
+
 func loadAnimation(path string) (*dc6.Animation, error) {
 	fp, err := os.Open(path)
 	if err != nil {
@@ -36,10 +72,19 @@ func loadAnimation(path string) (*dc6.Animation, error) {
 type scene struct {
 	animation *dc6.Animation
 	palette   *dat.Palette
+	blend     dat.BlendMode
+	colormap  *palshift.Colormap
+	shift     int
+	record    *animdata.Record
+	name      string
 	texture   graphics.Texture
 
 	directionIndex int
 	frameIndex     int
+	frameDuration  time.Duration
+	frameElapsed   time.Duration
+	lastAdvance    time.Time
+	playing        bool
 }
 
 func (s *scene) Name() string {
@@ -70,13 +115,26 @@ func (s *scene) Advance() error {
 
 	imgui.Begin("DC6 Viewer")
 	imgui.Image(s.texture)
-	direction := s.animation.Directions[directionIndex]
 	if imgui.SliderInt("Direction", &directionIndex, 0, len(s.animation.Directions)-1) {
 		frameIndex = 0
 	}
+	direction := s.animation.Directions[directionIndex]
+	if imgui.Checkbox("Play", &s.playing) {
+		s.frameElapsed = 0
+	}
+	if s.playing {
+		frameIndex = s.advanceFrame(frameIndex, len(direction.Frames))
+	}
+	s.lastAdvance = time.Now()
 	frame := direction.Frames[frameIndex]
 	imgui.SliderInt("Frame", &frameIndex, 0, len(direction.Frames)-1)
 	imgui.Columns(2)
+	if len(s.name) > 0 {
+		imgui.Text("Name")
+		imgui.NextColumn()
+		imgui.Text("%s", s.name)
+		imgui.NextColumn()
+	}
 	imgui.Text("Size")
 	imgui.NextColumn()
 	imgui.Text("%+v", frame.Size)
@@ -84,6 +142,16 @@ func (s *scene) Advance() error {
 	imgui.Text("Offset")
 	imgui.NextColumn()
 	imgui.Text("%+v", frame.Offset)
+	imgui.NextColumn()
+	imgui.Text("Frame time")
+	imgui.NextColumn()
+	imgui.Text("%v", s.frameDuration)
+	if s.record != nil {
+		imgui.NextColumn()
+		imgui.Text("Event")
+		imgui.NextColumn()
+		imgui.Text("%d", s.record.Event(frameIndex))
+	}
 	imgui.End()
 
 	if directionIndex != s.directionIndex || frameIndex != s.frameIndex {
@@ -95,15 +163,32 @@ func (s *scene) Advance() error {
 	return nil
 }
 
+func (s *scene) advanceFrame(frameIndex, frameCount int) int {
+	if s.frameDuration <= 0 || s.lastAdvance.IsZero() {
+		return frameIndex
+	}
+
+	s.frameElapsed += time.Since(s.lastAdvance)
+	for s.frameElapsed >= s.frameDuration {
+		s.frameElapsed -= s.frameDuration
+		frameIndex = (frameIndex + 1) % frameCount
+	}
+
+	return frameIndex
+}
+
 func (s *scene) updateTexture() error {
 	frame := s.animation.Directions[s.directionIndex].Frames[s.frameIndex]
-	colors := make([]math.Color3b, frame.Size.X*frame.Size.Y)
-	for y := 0; y < frame.Size.Y; y++ {
-		for x := 0; x < frame.Size.X; x++ {
-			colors[y*frame.Size.X+x] = s.palette.Colors[frame.Data[y*frame.Size.X+x]]
+	data := frame.Data
+	if s.colormap != nil {
+		var err error
+		if data, err = s.colormap.Remap(s.shift, data); err != nil {
+			return err
 		}
 	}
 
+	colors := s.palette.ToRGBA(data, dat.ColorOptions{Blend: s.blend})
+
 	if s.texture != nil {
 		if err := s.texture.Destroy(); err != nil {
 			return err
@@ -111,7 +196,7 @@ func (s *scene) updateTexture() error {
 	}
 
 	var err error
-	s.texture, err = platform.NewTextureFromRgb(colors, frame.Size)
+	s.texture, err = platform.NewTextureFromRgba(colors, frame.Size)
 	if err != nil {
 		return err
 	}
@@ -122,6 +207,15 @@ func (s *scene) updateTexture() error {
 func main() {
 	var (
 		palettePath = flag.String("palette", "", "path to palette file")
+		tablesPath  = flag.String("pl2", "", "path to palette transform file")
+		lightLevel  = flag.Int("light", -1, "light level transform to apply (0-31)")
+		remapPath   = flag.String("remap", "", "path to palette shift or colormap file")
+		remapIndex  = flag.Int("shift", 0, "index of the colormap to apply")
+		blendName   = flag.String("blend", "none", "blend mode (none, alpha25, alpha50, alpha75)")
+		animPath    = flag.String("animdata", "", "path to animation data file")
+		animName    = flag.String("anim", "", "animation name to look up (defaults to file name)")
+		stringsPath = flag.String("strings", "", "path to directory containing string tables")
+		nameKey     = flag.String("name", "", "string table key of the displayed name")
 	)
 
 	flag.Usage = func() {
@@ -137,6 +231,19 @@ func main() {
 		os.Exit(2)
 	}
 
+	blendModes := map[string]dat.BlendMode{
+		"none":    dat.BlendNone,
+		"alpha25": dat.BlendAlpha25,
+		"alpha50": dat.BlendAlpha50,
+		"alpha75": dat.BlendAlpha75,
+	}
+
+	blend, ok := blendModes[*blendName]
+	if !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+
 	if err := platform.Initialize(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -156,11 +263,86 @@ func main() {
 			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-	} else {
+	}
+
+	if len(*tablesPath) > 0 {
+		tables, err := loadTables(*tablesPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if palette == nil {
+			palette = &tables.Base
+		}
+
+		if *lightLevel >= 0 && *lightLevel < len(tables.LightLevels) {
+			palette = palette.Transform(&tables.LightLevels[*lightLevel])
+		}
+	}
+
+	if palette == nil {
 		palette = dat.NewFromGrayscale()
 	}
 
-	scene := &scene{animation: animation, palette: palette}
+	var colormap *palshift.Colormap
+	if len(*remapPath) > 0 {
+		colormap, err = loadColormap(*remapPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		if *remapIndex < 0 || *remapIndex >= len(colormap.Transforms) {
+			fmt.Fprintf(os.Stderr, "colormap index must be between 0 and %d\n", len(colormap.Transforms)-1)
+			os.Exit(1)
+		}
+	}
+
+	var record *animdata.Record
+	frameDuration := time.Second / 25
+	if len(*animPath) > 0 {
+		animData, err := loadAnimData(*animPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		name := *animName
+		if len(name) == 0 {
+			name = strings.TrimSuffix(filepath.Base(flag.Arg(0)), filepath.Ext(flag.Arg(0)))
+		}
+
+		if record = animData.Find(name); record == nil {
+			fmt.Fprintf(os.Stderr, "animation %s not found in animation data\n", name)
+			os.Exit(1)
+		}
+
+		frameDuration = record.FrameDuration()
+	}
+
+	var name string
+	if len(*stringsPath) > 0 {
+		stringTables, err := loadStrings(*stringsPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		name = tbl.StripColors(stringTables.Lookup(*nameKey))
+	}
+
+	scene := &scene{
+		animation:     animation,
+		palette:       palette,
+		blend:         blend,
+		colormap:      colormap,
+		shift:         *remapIndex,
+		record:        record,
+		name:          name,
+		frameDuration: frameDuration,
+		playing:       record != nil,
+	}
 	if err := platform.WindowCreate("Viewer", math.Vec2i{X: 1024, Y: 768}, scene); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -0,0 +1,154 @@
+package vfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+type StatFunc func(name string) (fs.FileInfo, error)
+
+func ValidPath(name string) bool {
+	return fs.ValidPath(name) && !strings.Contains(name, "\\")
+}
+
+func BuildDirs(names []string) map[string][]string {
+	dirs := map[string][]string{".": nil}
+
+	for _, name := range names {
+		for {
+			parent := path.Dir(name)
+			_, exists := dirs[parent]
+			dirs[parent] = append(dirs[parent], path.Base(name))
+			if exists || parent == "." {
+				break
+			}
+
+			name = parent
+		}
+	}
+
+	for _, entries := range dirs {
+		sort.Strings(entries)
+	}
+
+	return dirs
+}
+
+func Glob(dirs map[string][]string, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for dir, entries := range dirs {
+		for _, entry := range entries {
+			name := path.Join(dir, entry)
+			if match, _ := path.Match(pattern, name); match {
+				matches = append(matches, name)
+			}
+		}
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func ReadDir(name string, entries []string, stat StatFunc) ([]fs.DirEntry, error) {
+	var dirEntries []fs.DirEntry
+	for _, entry := range entries {
+		info, err := stat(path.Join(name, entry))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		dirEntries = append(dirEntries, fs.FileInfoToDirEntry(info))
+	}
+
+	return dirEntries, nil
+}
+
+type FileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func NewFileInfo(name string, size int64) FileInfo {
+	return FileInfo{name: path.Base(name), size: size}
+}
+
+func NewDirInfo(name string) FileInfo {
+	return FileInfo{name: path.Base(name), dir: true}
+}
+
+func (i FileInfo) Name() string {
+	return i.name
+}
+
+func (i FileInfo) Size() int64 {
+	return i.size
+}
+
+func (i FileInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0555
+	}
+
+	return 0444
+}
+
+func (i FileInfo) ModTime() time.Time {
+	return time.Time{}
+}
+
+func (i FileInfo) IsDir() bool {
+	return i.dir
+}
+
+func (i FileInfo) Sys() interface{} {
+	return nil
+}
+
+type Dir struct {
+	info    FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func NewDir(name string, entries []fs.DirEntry) *Dir {
+	return &Dir{info: NewDirInfo(name), entries: entries}
+}
+
+func (d *Dir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *Dir) Read(data []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+func (d *Dir) Close() error {
+	return nil
+}
+
+func (d *Dir) ReadDir(count int) ([]fs.DirEntry, error) {
+	entries := d.entries[d.offset:]
+	if count > 0 {
+		if len(entries) == 0 {
+			return nil, io.EOF
+		}
+		if count < len(entries) {
+			entries = entries[:count]
+		}
+	}
+
+	d.offset += len(entries)
+	return entries, nil
+}
@@ -0,0 +1,54 @@
+package vfs
+
+import (
+	"io/fs"
+	"reflect"
+	"testing"
+)
+
+func TestBuildDirs(t *testing.T) {
+	dirs := BuildDirs([]string{"data/global/items.txt", "data/global/armor.txt", "data/local/font", "readme.txt"})
+
+	expected := map[string][]string{
+		".":           {"data", "readme.txt"},
+		"data":        {"global", "local"},
+		"data/global": {"armor.txt", "items.txt"},
+		"data/local":  {"font"},
+	}
+
+	if !reflect.DeepEqual(dirs, expected) {
+		t.Fatalf("dirs: %v, expected: %v", dirs, expected)
+	}
+
+	matches, err := Glob(dirs, "data/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"data/global", "data/local"}; !reflect.DeepEqual(matches, expected) {
+		t.Errorf("glob matches: %v, expected: %v", matches, expected)
+	}
+
+	if _, err := Glob(dirs, "["); err == nil {
+		t.Error("invalid pattern was accepted")
+	}
+}
+
+func TestReadDir(t *testing.T) {
+	stat := func(name string) (fs.FileInfo, error) {
+		if name == "data/stale.txt" {
+			return nil, fs.ErrNotExist
+		}
+
+		return NewFileInfo(name, int64(len(name))), nil
+	}
+
+	entries, err := ReadDir("data", []string{"items.txt", "stale.txt"}, stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Name() != "items.txt" {
+		t.Errorf("entries: %v", entries)
+	}
+}